@@ -10,17 +10,31 @@ import (
   "bufio"
   "flag"
   "fmt"
+  "math"
+  "math/big"
   "os"
   "regexp"
   "slices"
+  "strings"
 )
 
 // ValidLineCheck - the measure of whether a line we read in is valid or not
-const ValidLineCheck string = `^[.#]+$`
+const ValidLineCheck string = `^[.#@1-9]+$`
+
+// GalaxyCheck - the measure of whether a symbol is a galaxy. A # is a galaxy with a mass of
+// 1, while the digits 1 to 9 are galaxies with that mass.
+const GalaxyCheck string = `[#1-9]`
+
+// ObstacleSymbol - the symbol for impassable matter, which nothing can walk through
+const ObstacleSymbol byte = '@'
 
 // debug - Choose whether to run the program in debug mode
 var debug = false
 
+// strict - Choose whether to reject maps with unexpected symbols or ragged lines, and
+// coordinate lists with lines that aren't coordinates, rather than skipping over them
+var strict = true
+
 // Print out a given line if debug is enabled during the runtime of this program
 func debugLine(lineToDebug string) {
   if debug {
@@ -28,7 +42,37 @@ func debugLine(lineToDebug string) {
   }
 }
 
-// Read in a given file and return each line in a slice
+// Given a symbol from a map of space, return whether it is a galaxy
+func isGalaxy(symbol byte) bool {
+  return symbol == '#' || (symbol >= '1' && symbol <= '9')
+}
+
+// Given a symbol from a map of space which is a galaxy, return the mass of that galaxy
+func galaxyMass(symbol byte) int {
+  if symbol == '#' {
+    return 1
+  }
+  return int(symbol - '0')
+}
+
+// Given the name of a file, the number of a line within it, the line itself and the width
+// that every line should be, return an error pointing at the first problem with the line
+// if there is one. Line numbers and columns both count from 1.
+func checkMapLine(filename string, lineNumber int, line string, width int) error {
+  for col := 0; col < len(line); col++ {
+    if !isGalaxy(line[col]) && line[col] != '.' && line[col] != ObstacleSymbol {
+      return fmt.Errorf("%v:%v:%v: unexpected symbol %q", filename, lineNumber, col + 1, line[col])
+    }
+  }
+  if len(line) != width {
+    return fmt.Errorf("%v:%v:%v: line is %v wide rather than %v", filename, lineNumber, min(len(line), width) + 1, len(line), width)
+  }
+  return nil
+}
+
+// Read in a given file and return each line in a slice. In strict mode, any line with an
+// unexpected symbol or a different width to the first is an error, while otherwise lines
+// with unexpected symbols are skipped over. Blank lines are always skipped.
 func readFile(filename string) ([]string, error) {
   var fileContents []string
 
@@ -41,101 +85,136 @@ func readFile(filename string) ([]string, error) {
   var lineRegex = regexp.MustCompile(ValidLineCheck)
 
   scanner := bufio.NewScanner(file)
+  lineNumber := 0
   for scanner.Scan() {
+    lineNumber++
     var line = scanner.Text()
-    if lineRegex.MatchString(line) {
+    if strict {
+      line = strings.TrimSuffix(line, "\r")
+      if strings.TrimSpace(line) == "" {
+        continue
+      }
+      width := len(line)
+      if len(fileContents) > 0 {
+        width = len(fileContents[0])
+      }
+      if err := checkMapLine(filename, lineNumber, line, width); err != nil {
+        return fileContents, err
+      }
+      fileContents = append(fileContents, line)
+    } else if lineRegex.MatchString(line) {
       fileContents = append(fileContents, line)
     }
   }
-  return fileContents, nil
+  return fileContents, scanner.Err()
 }
 
 // Given a set of lines which are in our space format, return two slices containing
-// the X columns and Y rows that do not contain any galaxies
+// the X rows and Y columns that do not contain any galaxies. This is done in a single pass
+// over the lines, with a bitset of the columns that have been seen to hold a galaxy so far.
 func findEmptySpace(fileContents []string) ([]int, []int) {
   var xLines []int
   var yLines []int
-  var lineLen = -1
-
-  var hashRegex = regexp.MustCompile(`#`)
-
-  for lineIdx := range fileContents {
-    // If we don't know the line length yet, we can find this out on the first
-    // iteration and pre-fill the yLines array with every possible position as
-    // we do not yet know which lines are empty
-    if lineLen == -1 {
-      lineLen = len(fileContents[lineIdx])
-      for i := 0; i < len(fileContents[lineIdx]); i++ {
-        yLines = append(yLines, i)
-      }
-    }
+  if len(fileContents) == 0 {
+    return xLines, yLines
+  }
 
-    // If the line contains any hashes at all, we can remove their positions from
-    // the Y lines as we have found proof that they exist.
-    if hashRegex.MatchString(fileContents[lineIdx]) {
-      var locations = hashRegex.FindAllStringIndex(fileContents[lineIdx], -1)
-      for locIdx := range locations {
-        for idx, loc := range yLines {
-          if loc == locations[locIdx][0] {
-            yLines = slices.Delete(yLines, idx, idx + 1)
-          }
-        }
+  // Every line is expected to be as wide as the first, which gives us the number of
+  // columns to track. Each uint64 in the bitset tracks 64 of those columns.
+  var lineLen = len(fileContents[0])
+  var occupied = make([]uint64, (lineLen + 63) / 64)
+
+  for lineIdx, line := range fileContents {
+    // Mark off the column of every galaxy we find. If the line doesn't contain any galaxies
+    // at all, it is an empty X row.
+    rowEmpty := true
+    for col := 0; col < len(line); col++ {
+      if !isGalaxy(line[col]) {
+        continue
+      }
+      rowEmpty = false
+      if col < lineLen {
+        occupied[col / 64] |= 1 << (col % 64)
       }
-    } else {
-      // Otherwise, the line contains no hashes and is an empty X row.
+    }
+    if rowEmpty {
       xLines = append(xLines, lineIdx)
     }
   }
 
+  // Any column which was never marked off is an empty Y column.
+  for col := 0; col < lineLen; col++ {
+    if occupied[col / 64] & (1 << (col % 64)) == 0 {
+      yLines = append(yLines, col)
+    }
+  }
+
   return xLines, yLines
 }
 
-// Given a map of space which is several lines containing .s and #s, along with the
-// desired expansion rate, the slice of empty X rows and Y columns, we return a map
-// of all galaxies within the map, along with their expanded positions.
-func extractGalaxies(spaceMap []string, expansionRate int, xLines []int, yLines []int) []map[string]int {
+// Given a map of space which is several lines containing .s and galaxies, along with the
+// desired expansion rates for the X and Y axis, the slice of empty X rows and Y columns,
+// we return a map of all galaxies within the map, along with their original and expanded
+// positions and their mass. Empty Y columns push galaxies along the X axis, while empty
+// X rows push galaxies along the Y axis.
+func extractGalaxies(spaceMap []string, xRate int, yRate int, xLines []int, yLines []int) []map[string]int {
   var galaxyList []map[string]int
-  var hashRegex = regexp.MustCompile(`#`)
+  var galaxyRegex = regexp.MustCompile(GalaxyCheck)
 
   for i := 0; i < len(spaceMap); i++ {
-    // Find the positions of all hashes within a given line
-    var locations = hashRegex.FindAllStringIndex(spaceMap[i], -1)
+    // Find the positions of all galaxies within a given line
+    var locations = galaxyRegex.FindAllStringIndex(spaceMap[i], -1)
     for locIdx := range locations {
       // And calculate the modified X location, which is the position within the regex
-      // search, + x(expansionRate), where x is the number of Y columns that the hash
-      // appears after that contain empty space.
+      // search, + x(xRate), where x is the number of Y columns that the hash appears
+      // after that contain empty space.
       xLoc := locations[locIdx][0]
       for _, yLine := range yLines {
         if yLine < locations[locIdx][0] {
-          xLoc += expansionRate
+          xLoc += xRate
         }
       }
 
-      // Calculate the modified Y location, which is the current row + x(expansionRate),
-      // where x is the number of X rows that the # appears after that contain empty space.
+      // Calculate the modified Y location, which is the current row + x(yRate), where x
+      // is the number of X rows that the # appears after that contain empty space.
       yLoc := i
       for _, xLine := range xLines {
         if xLine < i {
-          yLoc += expansionRate
+          yLoc += yRate
         }
       }
 
       // Create and fill in the map representation of the galaxy and add it to the list.
       tmpMap := make(map[string]int)
+      tmpMap["ox"] = locations[locIdx][0]
+      tmpMap["oy"] = i
       tmpMap["x"] = xLoc
       tmpMap["y"] = yLoc
+      tmpMap["mass"] = galaxyMass(spaceMap[i][locations[locIdx][0]])
       galaxyList = append(galaxyList, tmpMap)
     }
   }
   return galaxyList
 }
 
+// Given a map of space, the expansion rates for the X and Y axis and the slice of empty X
+// rows and Y columns, return the width and height of the universe once it has expanded.
+func expandedSize(spaceMap []string, xRate int, yRate int, xLines []int, yLines []int) (int, int) {
+  if len(spaceMap) == 0 {
+    return 0, 0
+  }
+  return len(spaceMap[0]) + len(yLines) * xRate, len(spaceMap) + len(xLines) * yRate
+}
+
 // Given a list of galaxies (which contain and x and y coordinate), calculate the number of
-// steps between every galaxy. The number of galaxy paths that need checking correlates
-// exponentially with the number of galaxies - where 2 galaxies return 1 path, 3 generates
-// 3 paths, 4 generates 6, and so on.
-func calculateStepsBetweenGalaxies(galaxyList []map[string]int) int {
-  stepCount := 0
+// steps between every galaxy by comparing every pair in turn. The number of galaxy paths
+// that need checking correlates exponentially with the number of galaxies - where 2
+// galaxies return 1 path, 3 generates 3 paths, 4 generates 6, and so on.
+//
+// This is kept around behind the -pairwise flag to cross-check the sorted approach below,
+// so the total is always kept with big integers to make sure it is exact.
+func calculateStepsBetweenGalaxiesPairwise(galaxyList []map[string]int) *big.Int {
+  stepCount := new(big.Int)
 
   // We do an inverse for loop to avoid replaying any step counts that we have already
   // calculated.
@@ -151,41 +230,476 @@ func calculateStepsBetweenGalaxies(galaxyList []map[string]int) int {
       if ySteps < 0 {
         ySteps = -ySteps
       }
-      stepCount += xSteps + ySteps
+      stepCount.Add(stepCount, big.NewInt(int64(xSteps + ySteps)))
     }
   }
   return stepCount
 }
 
+// Given a list of galaxies (which contain and x and y coordinate), calculate the number of
+// steps between every galaxy. As the steps between two galaxies are the X steps plus the
+// Y steps, we can total up each axis on its own without ever looking at a pair directly.
+func calculateStepsBetweenGalaxies(galaxyList []map[string]int) int {
+  return sumAxisDistances(galaxyList, "x") + sumAxisDistances(galaxyList, "y")
+}
+
+// Given a list of galaxies and the axis to look at, sum up the distance between every pair
+// of galaxies along that axis.
+func sumAxisDistances(galaxyList []map[string]int, axis string) int {
+  return sumPairDifferences(axisPositions(galaxyList, axis))
+}
+
+// Given a list of galaxies and the axis to look at, pull out the position of every galaxy
+// along that axis into its own slice.
+func axisPositions(galaxyList []map[string]int, axis string) []int {
+  positions := make([]int, len(galaxyList))
+  for idx, galaxy := range galaxyList {
+    positions[idx] = galaxy[axis]
+  }
+  return positions
+}
+
+// Given a list of positions along a line, sum up the distance between every pair of them.
+// Once the positions are sorted, the position at index i sits after i others, so its
+// distance to all of them is i * position minus the sum of every position before it -
+// which we can keep as a running total. The positions are sorted in place.
+func sumPairDifferences(positions []int) int {
+  slices.Sort(positions)
+
+  stepCount := 0
+  prefixSum := 0
+  for idx, position := range positions {
+    stepCount += position * idx - prefixSum
+    prefixSum += position
+  }
+  return stepCount
+}
+
+// Given a list of galaxies, work out whether the total steps between them could grow past
+// what fits in an int. Every pair is at most the full width plus the full height apart, so
+// if that many steps for every pair still fits, the sorted approach can never wrap.
+func stepsMayOverflow(galaxyList []map[string]int) bool {
+  if len(galaxyList) < 2 {
+    return false
+  }
+
+  minX, maxX := galaxyList[0]["x"], galaxyList[0]["x"]
+  minY, maxY := galaxyList[0]["y"], galaxyList[0]["y"]
+  for _, galaxy := range galaxyList {
+    minX = min(minX, galaxy["x"])
+    maxX = max(maxX, galaxy["x"])
+    minY = min(minY, galaxy["y"])
+    maxY = max(maxY, galaxy["y"])
+  }
+
+  galaxyCount := big.NewInt(int64(len(galaxyList)))
+  pairCount := new(big.Int).Mul(galaxyCount, new(big.Int).Sub(galaxyCount, big.NewInt(1)))
+  pairCount.Rsh(pairCount, 1)
+
+  span := new(big.Int).Sub(big.NewInt(int64(maxX)), big.NewInt(int64(minX)))
+  span.Add(span, new(big.Int).Sub(big.NewInt(int64(maxY)), big.NewInt(int64(minY))))
+
+  upperBound := new(big.Int).Mul(pairCount, span)
+  return upperBound.Cmp(big.NewInt(math.MaxInt)) > 0
+}
+
+// Given a list of galaxies, calculate the number of steps between every galaxy in the same
+// way as calculateStepsBetweenGalaxies, but using big integers so that the total can never
+// wrap around, no matter how far apart the galaxies have been pushed.
+func calculateStepsBetweenGalaxiesBig(galaxyList []map[string]int) *big.Int {
+  stepCount := sumAxisDistancesBig(galaxyList, "x")
+  return stepCount.Add(stepCount, sumAxisDistancesBig(galaxyList, "y"))
+}
+
+// Given a list of galaxies and the axis to look at, sum up the distance between every pair
+// of galaxies along that axis with big integers.
+func sumAxisDistancesBig(galaxyList []map[string]int, axis string) *big.Int {
+  return sumPairDifferencesBig(axisPositions(galaxyList, axis))
+}
+
+// Given a list of positions along a line, sum up the distance between every pair of them
+// with big integers, following sumPairDifferences. The positions are sorted in place.
+func sumPairDifferencesBig(positions []int) *big.Int {
+  slices.Sort(positions)
+
+  stepCount := new(big.Int)
+  prefixSum := new(big.Int)
+  for idx, position := range positions {
+    bigPosition := big.NewInt(int64(position))
+    stepCount.Add(stepCount, new(big.Int).Mul(bigPosition, big.NewInt(int64(idx))))
+    stepCount.Sub(stepCount, prefixSum)
+    prefixSum.Add(prefixSum, bigPosition)
+  }
+  return stepCount
+}
+
+// Given a map of space, return the size of its longest side
+func spaceMapSize(spaceMap []string) int {
+  mapSize := len(spaceMap)
+  for _, line := range spaceMap {
+    mapSize = max(mapSize, len(line))
+  }
+  return mapSize
+}
+
+// Given the size of the longest side of a universe and the expansion rate, check that
+// every expanded coordinate still fits into an int. Each line can be pushed along by at
+// most one expansion for every line before it, so the furthest point is the size of the
+// universe multiplied by one more than the expansion rate. We leave room for twice that,
+// so that an X and Y coordinate can always be added together.
+func expansionFits(mapSize int, expansionRate int) bool {
+  if mapSize == 0 || expansionRate < 0 {
+    return true
+  }
+  return expansionRate < math.MaxInt / (2 * mapSize)
+}
+
 // Main function to kick the work
 func main() {
   // Do some initial CLI parsing to figure out what the requested operation is.
   var filename string
-  var expansionRate int
+  var inputFormat string
+  var expansionRateText string
+  var xRateText string
+  var yRateText string
+  var zRateText string
+  var pairwise bool
+  var forceBig bool
+  var metric string
+  var knn int
+  var clusterLimit int
+  var spanningTree bool
+  var tour bool
+  var paths bool
+  var voronoi bool
+  var wrap bool
+  var weighted bool
+  var stats bool
+  var scriptFile string
+  var queryFile string
+  var diffFile string
+  var profileSpec string
+  var formula bool
+  var rateList string
+  var target string
+  var exportFile string
+  var renderFile string
+  var exportFormat string
   flag.StringVar(&filename, "i", "input.txt", "Specify input file for the program")
-  flag.IntVar(&expansionRate, "e", 1, "Specify the rate of expansion")
+  flag.StringVar(&inputFormat, "inputfmt", "auto", "Format of the input file (auto, dense, sparse, volume)")
+  flag.StringVar(&expansionRateText, "e", "1", "Specify the rate of expansion, as a whole number, decimal or fraction")
+  flag.StringVar(&xRateText, "ex", "1", "Specify the rate of expansion along the X axis (default -e)")
+  flag.StringVar(&yRateText, "ey", "1", "Specify the rate of expansion along the Y axis (default -e)")
+  flag.StringVar(&zRateText, "ez", "1", "Specify the rate of expansion along the Z axis of a volume (default -e)")
+  flag.StringVar(&profileSpec, "profile", "", "Grow empty lines by a profile (table:file, geometric:ratio, runs:rate,...)")
+  flag.BoolVar(&pairwise, "pairwise", false, "Compare every pair of galaxies directly")
+  flag.BoolVar(&forceBig, "big", false, "Always total the steps with big integers")
+  flag.StringVar(&metric, "metric", "manhattan", "Distance metric to use (manhattan, chebyshev, euclidean)")
+  flag.IntVar(&knn, "knn", 0, "Print the k nearest galaxies to every galaxy")
+  flag.IntVar(&clusterLimit, "cluster", -1, "Group galaxies which are within the given distance of each other")
+  flag.BoolVar(&spanningTree, "mst", false, "Find the minimum spanning tree over every galaxy")
+  flag.BoolVar(&tour, "tour", false, "Find a short round trip which visits every galaxy")
+  flag.BoolVar(&paths, "paths", false, "Walk the shortest paths between galaxies around any obstacles")
+  flag.BoolVar(&voronoi, "voronoi", false, "Split the expanded universe into the territory of each galaxy")
+  flag.BoolVar(&formula, "formula", false, "Print the total as a formula of the expansion rate")
+  flag.StringVar(&rateList, "rates", "", "Comma separated rates or start..end ranges to answer with the formula")
+  flag.StringVar(&target, "target", "", "Find the expansion rate which gives this total")
+  flag.BoolVar(&wrap, "wrap", false, "Treat the universe as wrapping around at its edges")
+  flag.BoolVar(&weighted, "weighted", false, "Weight the distances by the mass of each galaxy")
+  flag.BoolVar(&stats, "stats", false, "Print statistics and a histogram of the pair distances")
+  flag.StringVar(&scriptFile, "script", "", "Run a script of add, del and total commands against the universe")
+  flag.StringVar(&queryFile, "query", "", "Answer rectangle queries from the given file")
+  flag.StringVar(&diffFile, "diff", "", "Compare the input file against a later observation in the given file")
+  flag.StringVar(&exportFile, "export", "", "Write every pair of galaxies out to the given file")
+  flag.StringVar(&exportFormat, "exportfmt", "csv", "Format of the pair export (csv, json)")
+  flag.StringVar(&renderFile, "render", "", "Draw the expanded universe to the given text or .png file")
+  flag.BoolVar(&strict, "strict", true, "Reject maps with unexpected symbols or ragged lines, and invalid coordinates")
   flag.BoolVar(&debug, "debug", false, "Enable debug logging")
   flag.Parse()
 
-  // Read in the given file as a number of lines.
-  fileContents, err := readFile(filename)
-  if err != nil {
-    fmt.Println(err)
+  // The X and Y expansion rates fall back to the shared expansion rate unless they have
+  // been set on their own.
+  var setFlags = make(map[string]bool)
+  flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+  if !setFlags["ex"] {
+    xRateText = expansionRateText
+  }
+  if !setFlags["ey"] {
+    yRateText = expansionRateText
+  }
+  if !setFlags["ez"] {
+    zRateText = expansionRateText
+  }
+
+  // Rates are read in exactly, and any that are fractions (or too large to place galaxies
+  // with) mean that only the exact total can be worked out.
+  var rates [3]*big.Rat
+  var wholeRates [3]int
+  fractional := false
+  for idx, rateText := range []string{xRateText, yRateText, zRateText} {
+    var err error
+    rates[idx], err = parseRate(rateText)
+    if err != nil {
+      fmt.Println(err)
+      os.Exit(1)
+    }
+    var isWhole bool
+    wholeRates[idx], isWhole = wholeRate(rates[idx])
+    fractional = fractional || !isWhole
+  }
+  xRate, yRate, zRate := wholeRates[0], wholeRates[1], wholeRates[2]
+
+  // Only one mode can be run at once, so make sure that no more than one has been asked
+  // for rather than quietly running the first.
+  var modes []string
+  for _, mode := range []struct {
+    name string
+    active bool
+  }{
+    {"-knn", knn > 0},
+    {"-cluster", clusterLimit >= 0},
+    {"-mst/-tour", spanningTree || tour},
+    {"-formula/-rates/-target", formula || rateList != "" || target != ""},
+    {"-paths", paths},
+    {"-voronoi", voronoi},
+    {"-diff", diffFile != ""},
+    {"-query", queryFile != ""},
+    {"-script", scriptFile != ""},
+    {"-stats", stats},
+    {"-weighted", weighted},
+    {"-wrap", wrap},
+  } {
+    if mode.active {
+      modes = append(modes, mode.name)
+    }
+  }
+  if len(modes) > 1 {
+    fmt.Printf("Only one mode can be run at once, but %v were asked for\n", strings.Join(modes, ", "))
     os.Exit(1)
   }
 
-  // Calculate the empty rows and columns within the file and debug that out to
-  // the console.
-  xLines, yLines := findEmptySpace(fileContents)
-  debugLine(fmt.Sprintf("Found empty X lines at %v", xLines))
-  debugLine(fmt.Sprintf("Found empty Y lines at %v", yLines))
+  // Expansion profiles don't grow every empty line by the same rate, so they can't be used
+  // alongside anything that relies on a single rate.
+  var profile ExpansionProfile
+  if profileSpec != "" {
+    if formula || rateList != "" || target != "" || paths || voronoi || renderFile != "" || scriptFile != "" || queryFile != "" || diffFile != "" || wrap {
+      fmt.Println("Expansion profiles can't be combined with formulas, paths, territories, renders, scripts, queries, differences or wrapping")
+      os.Exit(1)
+    }
+    var err error
+    profile, err = parseExpansionProfile(profileSpec)
+    if err != nil {
+      fmt.Println(err)
+      os.Exit(1)
+    }
+  }
+
+  // Work out whether the input file is a map of space, or a sparse list of coordinates.
+  if inputFormat == "auto" {
+    var err error
+    inputFormat, err = detectInputFormat(filename)
+    if err != nil {
+      fmt.Println(err)
+      os.Exit(1)
+    }
+    debugLine(fmt.Sprintf("Detected %v input", inputFormat))
+  }
+
+  var fileContents []string
+  var xLines []int
+  var yLines []int
+  var mapSize int
+  var expand func(int, int) []map[string]int
+  var width, height int
+
+  switch inputFormat {
+  case "dense":
+    // Read in the given file as a number of lines.
+    var err error
+    fileContents, err = readFile(filename)
+    if err != nil {
+      fmt.Println(err)
+      os.Exit(1)
+    }
+
+    // Calculate the empty rows and columns within the file and debug that out to
+    // the console.
+    xLines, yLines = findEmptySpace(fileContents)
+    debugLine(fmt.Sprintf("Found empty X lines at %v", xLines))
+    debugLine(fmt.Sprintf("Found empty Y lines at %v", yLines))
+
+    mapSize = spaceMapSize(fileContents)
+    expand = func(xRate int, yRate int) []map[string]int {
+      return extractGalaxies(fileContents, xRate, yRate, xLines, yLines)
+    }
+    width, height = expandedSize(fileContents, xRate, yRate, xLines, yLines)
+
+  case "sparse":
+    // Read in the given file as a list of coordinates. The empty rows and columns are far
+    // too many to list out, so they are worked out from the coordinates as we go.
+    coordinates, err := readCoordinates(filename)
+    if err != nil {
+      fmt.Println(err)
+      os.Exit(1)
+    }
+    if paths || voronoi || renderFile != "" || diffFile != "" {
+      fmt.Println("Paths, territories, renders and differences need a map of space rather than a list of coordinates")
+      os.Exit(1)
+    }
+
+    mapSize = coordinatesSize(coordinates)
+    expand = func(xRate int, yRate int) []map[string]int {
+      return extractSparseGalaxies(coordinates, xRate, yRate)
+    }
+    width, height = sparseExpandedSize(coordinates, xRate, yRate)
+
+  case "volume":
+    // Volumes only support totalling the steps between galaxies, so anything else that has
+    // been asked for can't be done.
+    for name := range setFlags {
+      if !slices.Contains([]string{"i", "inputfmt", "e", "ex", "ey", "ez", "metric", "pairwise", "big", "strict", "debug"}, name) {
+        fmt.Printf("The -%v flag can't be used with a volume\n", name)
+        os.Exit(1)
+      }
+    }
+
+    layers, err := readVolume(filename)
+    if err != nil {
+      fmt.Println(err)
+      os.Exit(1)
+    }
+    // Any rate which would push galaxies too far to place them is worked out exactly
+    // instead, in the same way as fractional rates.
+    exact := fractional
+    for _, rate := range []int{xRate, yRate, zRate} {
+      exact = exact || !expansionFits(volumeSize(layers), rate)
+    }
+    if exact {
+      if pairwise {
+        fmt.Println("The -pairwise flag can't be used with a fractional or very large expansion rate")
+        os.Exit(1)
+      }
+      err = printRationalVolumeDistances(layers, rates, metric)
+      if err != nil {
+        fmt.Println(err)
+        os.Exit(1)
+      }
+      return
+    }
+
+    galaxies := extractVolumeGalaxies(layers, xRate, yRate, zRate)
+    debugLine(fmt.Sprintf("Expanding X by %v, Y by %v and Z by %v", xRate, yRate, zRate))
+    for idx, galaxy := range galaxies {
+      debugLine(fmt.Sprintf("Galaxy %v at (%v,%v,%v) expanded to (%v,%v,%v)", idx + 1, galaxy["ox"], galaxy["oy"], galaxy["oz"], galaxy["x"], galaxy["y"], galaxy["z"]))
+    }
+    err = printVolumeDistances(galaxies, metric, pairwise)
+    if err != nil {
+      fmt.Println(err)
+      os.Exit(1)
+    }
+    return
+
+  default:
+    fmt.Printf("Unknown input format %q\n", inputFormat)
+    os.Exit(1)
+  }
+
+  // Galaxies can't be placed at fractional rates, or at rates so large that their
+  // coordinates would wrap around. Those rates only support the exact total, so there is
+  // nothing else to do once it has been printed.
+  exact := fractional
+  for _, rate := range []int{xRate, yRate} {
+    exact = exact || !expansionFits(mapSize, rate)
+  }
+  if exact {
+    for name := range setFlags {
+      if !slices.Contains([]string{"i", "inputfmt", "e", "ex", "ey", "metric", "big", "strict", "debug"}, name) {
+        fmt.Printf("The -%v flag can't be used with a fractional or very large expansion rate\n", name)
+        os.Exit(1)
+      }
+    }
+    err := printRationalDistances(expand, rates[0], rates[1], metric)
+    if err != nil {
+      fmt.Println(err)
+      os.Exit(1)
+    }
+    return
+  }
 
   // Find the galaxies that are contained within the space map along with and track
   // them with their expanded coordinates, then print them as debug.
-  galaxies := extractGalaxies(fileContents, expansionRate, xLines, yLines)
-  debugLine(fmt.Sprintf("Found galaxies %v", galaxies))
+  galaxies := expand(xRate, yRate)
+  debugLine(fmt.Sprintf("Expanding X by %v and Y by %v", xRate, yRate))
+  if profileSpec != "" {
+    err := profile.apply(galaxies, xRate, yRate)
+    if err != nil {
+      fmt.Println(err)
+      os.Exit(1)
+    }
+    debugLine(fmt.Sprintf("Expanding with the %v profile instead", profile.kind))
+  }
+  for idx, galaxy := range galaxies {
+    debugLine(fmt.Sprintf("Galaxy %v at (%v,%v) expanded to (%v,%v)", idx + 1, galaxy["ox"], galaxy["oy"], galaxy["x"], galaxy["y"]))
+  }
+
+  var err error
+
+  // If we've been asked to, write every pair of galaxies out to a file before moving on.
+  if exportFile != "" {
+    err = exportGalaxyPairs(galaxies, exportFile, exportFormat, metric)
+    if err != nil {
+      fmt.Println(err)
+      os.Exit(1)
+    }
+    debugLine(fmt.Sprintf("Exported galaxy pairs to %v", exportFile))
+  }
 
-  // Calculate the steps between all galaxies, then return that as output.
-  steps := calculateStepsBetweenGalaxies(galaxies)
-  fmt.Println(steps)
+  // If we've been asked to, draw the expanded universe out to a file before moving on.
+  // Territories are drawn out as a map of who owns each cell instead.
+  if renderFile != "" && !voronoi {
+    err = renderUniverse(fileContents, galaxies, xRate, yRate, xLines, yLines, renderFile)
+    if err != nil {
+      fmt.Println(err)
+      os.Exit(1)
+    }
+    debugLine(fmt.Sprintf("Rendered expanded universe to %v", renderFile))
+  }
+
+  // Either find the nearest neighbours of every galaxy, group them into clusters, plan
+  // routes between them, work out the formula for the total, walk the paths between them,
+  // split the universe into their territories, compare them against another map, run a
+  // script of changes against them, answer queries over them, report statistics on them,
+  // weigh them up by mass, or calculate the distance between all galaxies under the chosen
+  // metric (wrapping around the edges if asked to), then return that as output.
+  if knn > 0 {
+    err = printNearestNeighbours(galaxies, knn, metric)
+  } else if clusterLimit >= 0 {
+    err = printGalaxyClusters(galaxies, clusterLimit, metric, forceBig)
+  } else if spanningTree || tour {
+    err = printGalaxyRoutes(galaxies, metric, spanningTree, tour)
+  } else if formula || rateList != "" || target != "" {
+    err = printExpansionFormula(expand, rateList, target)
+  } else if paths {
+    err = printShortestPaths(fileContents, galaxies, xRate, yRate, xLines, yLines)
+  } else if voronoi {
+    err = printTerritories(fileContents, galaxies, xRate, yRate, xLines, yLines, renderFile)
+  } else if diffFile != "" {
+    err = printUniverseDiff(fileContents, diffFile, xRate, yRate, metric)
+  } else if queryFile != "" {
+    err = printRectangleQueries(galaxies, queryFile, xRate, yRate)
+  } else if scriptFile != "" {
+    err = runUniverseScript(galaxies, scriptFile, xRate, yRate)
+  } else if stats {
+    err = printDistanceStats(galaxies, metric)
+  } else if weighted {
+    err = printWeightedDistances(galaxies, metric)
+  } else if wrap {
+    err = printWrappedDistances(galaxies, width, height, metric, pairwise, forceBig)
+  } else {
+    err = printGalaxyDistances(galaxies, metric, pairwise, forceBig)
+  }
+  if err != nil {
+    fmt.Println(err)
+    os.Exit(1)
+  }
 }
@@ -0,0 +1,134 @@
+// Galaxy clustering for day 11. Galaxies which are within a given distance of each other
+// are joined together into clusters, with any galaxies that are joined to the same galaxy
+// ending up in the same cluster.
+//
+// To avoid comparing every pair of galaxies, each galaxy is dropped into a square bucket
+// as wide as the distance limit. Under every metric we support, two galaxies within the
+// limit are at most that far apart along each axis, so they always sit in the same or
+// neighbouring buckets.
+package main
+
+import (
+  "fmt"
+  "slices"
+)
+
+// GalaxyClusters - A union-find structure over galaxies. Each galaxy points at a parent
+// galaxy in the same cluster, with the root of each cluster pointing at itself and
+// tracking the number of galaxies within it.
+type GalaxyClusters struct {
+  parent []int
+  size []int
+}
+
+// Given a number of galaxies, create a set of clusters with each galaxy on its own
+func newGalaxyClusters(galaxyCount int) GalaxyClusters {
+  var clusters GalaxyClusters
+  clusters.parent = make([]int, galaxyCount)
+  clusters.size = make([]int, galaxyCount)
+  for idx := range clusters.parent {
+    clusters.parent[idx] = idx
+    clusters.size[idx] = 1
+  }
+  return clusters
+}
+
+// Given a galaxy, find the root galaxy of its cluster. Every galaxy visited on the way is
+// pointed further up the tree, which keeps later lookups short.
+func (clusters GalaxyClusters) find(galaxy int) int {
+  for clusters.parent[galaxy] != galaxy {
+    clusters.parent[galaxy] = clusters.parent[clusters.parent[galaxy]]
+    galaxy = clusters.parent[galaxy]
+  }
+  return galaxy
+}
+
+// Given two galaxies, join their clusters together by hanging the smaller cluster from
+// the root of the larger one.
+func (clusters GalaxyClusters) union(galaxyA int, galaxyB int) {
+  rootA, rootB := clusters.find(galaxyA), clusters.find(galaxyB)
+  if rootA == rootB {
+    return
+  }
+  if clusters.size[rootA] < clusters.size[rootB] {
+    rootA, rootB = rootB, rootA
+  }
+  clusters.parent[rootB] = rootA
+  clusters.size[rootA] += clusters.size[rootB]
+}
+
+// Given a list of galaxies, a distance limit and the metric to measure with, join every
+// pair of galaxies that are within the limit of each other into clusters.
+func clusterGalaxies(galaxyList []map[string]int, limit int, metric string) GalaxyClusters {
+  clusters := newGalaxyClusters(len(galaxyList))
+  bucketSize := max(limit, 1)
+
+  var buckets = make(map[[2]int][]int)
+  for idx, galaxy := range galaxyList {
+    bucket := [2]int{galaxy["x"] / bucketSize, galaxy["y"] / bucketSize}
+    buckets[bucket] = append(buckets[bucket], idx)
+  }
+
+  // Compare each galaxy with every galaxy in its own bucket and the eight around it. Only
+  // galaxies with a higher index are checked, so that each pair is only looked at once.
+  for idx, galaxy := range galaxyList {
+    bucket := [2]int{galaxy["x"] / bucketSize, galaxy["y"] / bucketSize}
+    for dy := -1; dy <= 1; dy++ {
+      for dx := -1; dx <= 1; dx++ {
+        for _, other := range buckets[[2]int{bucket[0] + dx, bucket[1] + dy}] {
+          if other > idx && withinDistance(metric, galaxy, galaxyList[other], limit) {
+            clusters.union(idx, other)
+          }
+        }
+      }
+    }
+  }
+  return clusters
+}
+
+// Given a list of galaxies, a distance limit and the metric to measure with, print out
+// every cluster of galaxies along with its size, the box in expanded space that holds it,
+// and the total distance between every galaxy within it. Clusters are listed in the order
+// of their lowest numbered galaxy.
+func printGalaxyClusters(galaxyList []map[string]int, limit int, metric string, forceBig bool) error {
+  if _, err := distanceFunc(metric); err != nil {
+    return err
+  }
+  if limit < 0 {
+    return fmt.Errorf("cluster distance must be 0 or more")
+  }
+
+  clusters := clusterGalaxies(galaxyList, limit, metric)
+
+  var members = make(map[int][]int)
+  var roots []int
+  for idx := range galaxyList {
+    root := clusters.find(idx)
+    if _, seen := members[root]; !seen {
+      roots = append(roots, root)
+    }
+    members[root] = append(members[root], idx)
+  }
+
+  for clusterIdx, root := range roots {
+    var clusterMembers []map[string]int
+    var galaxyNumbers []int
+    for _, idx := range members[root] {
+      clusterMembers = append(clusterMembers, galaxyList[idx])
+      galaxyNumbers = append(galaxyNumbers, idx + 1)
+    }
+
+    xPositions := axisPositions(clusterMembers, "x")
+    yPositions := axisPositions(clusterMembers, "y")
+    total, err := totalGalaxyDistances(clusterMembers, metric, false, forceBig)
+    if err != nil {
+      return err
+    }
+
+    fmt.Printf("Cluster %v: size %v, box (%v,%v)-(%v,%v), distance %v, galaxies %v\n",
+      clusterIdx + 1, len(galaxyNumbers),
+      slices.Min(xPositions), slices.Min(yPositions), slices.Max(xPositions), slices.Max(yPositions),
+      total, galaxyNumbers)
+  }
+  return nil
+}
@@ -0,0 +1,217 @@
+// Differences for day 11. Given two observations of the same sky, we want to know which
+// galaxies have come and gone, which rows and columns have emptied out or filled up, and
+// how that has changed the total steps between every galaxy. Galaxies are matched up
+// between the two maps by their original position.
+package main
+
+import (
+  "fmt"
+  "math/big"
+  "slices"
+  "strings"
+)
+
+// DiffContributors - the number of galaxies to list as making the largest change to the
+// total between two maps
+const DiffContributors int = 5
+
+// GalaxyChange - A galaxy from either map, along with the total steps from it to every
+// other galaxy in each map. Galaxies missing from a map have a total of 0 in that map.
+type GalaxyChange struct {
+  position [2]int
+  before *big.Int
+  after *big.Int
+  change *big.Int
+}
+
+// Given a list of galaxies, return the total steps from each galaxy to every other one.
+// Along each axis, the galaxies are sorted so that the steps to everything before and
+// after a galaxy can be found from running totals of the positions.
+func galaxyDistanceSums(galaxyList []map[string]int) []*big.Int {
+  var sums = make([]*big.Int, len(galaxyList))
+  for idx := range sums {
+    sums[idx] = new(big.Int)
+  }
+
+  for _, axis := range []string{"x", "y"} {
+    order := make([]int, len(galaxyList))
+    for idx := range order {
+      order[idx] = idx
+    }
+    slices.SortFunc(order, func(a int, b int) int {
+      return galaxyList[a][axis] - galaxyList[b][axis]
+    })
+
+    remaining := new(big.Int)
+    for _, idx := range order {
+      remaining.Add(remaining, big.NewInt(int64(galaxyList[idx][axis])))
+    }
+    before := new(big.Int)
+    for rank, idx := range order {
+      position := big.NewInt(int64(galaxyList[idx][axis]))
+      remaining.Sub(remaining, position)
+
+      // position * rank - before + remaining - position * (galaxies after this one)
+      steps := new(big.Int).Mul(position, big.NewInt(int64(rank)))
+      steps.Sub(steps, before)
+      steps.Add(steps, remaining)
+      steps.Sub(steps, new(big.Int).Mul(position, big.NewInt(int64(len(order) - rank - 1))))
+      sums[idx].Add(sums[idx], steps)
+
+      before.Add(before, position)
+    }
+  }
+  return sums
+}
+
+// Given two sorted lists of lines, return the lines which are only in the first list and
+// fall within the given number of lines
+func linesOnlyIn(first []int, second []int, lineCount int) []int {
+  var only []int
+  for _, line := range first {
+    if _, found := slices.BinarySearch(second, line); !found && line < lineCount {
+      only = append(only, line)
+    }
+  }
+  return only
+}
+
+// Given a label and a list of positions, print them out on a single line
+func printDiffPositions(label string, positions [][2]int) {
+  var entries []string
+  for _, position := range positions {
+    entries = append(entries, fmt.Sprintf("(%v,%v)", position[0], position[1]))
+  }
+  if len(entries) == 0 {
+    entries = append(entries, "none")
+  }
+  fmt.Printf("%v: %v\n", label, strings.Join(entries, ", "))
+}
+
+// Given a label and a list of lines, print them out on a single line
+func printDiffLines(label string, lines []int) {
+  var entries []string
+  for _, line := range lines {
+    entries = append(entries, fmt.Sprint(line))
+  }
+  if len(entries) == 0 {
+    entries = append(entries, "none")
+  }
+  fmt.Printf("%v: %v\n", label, strings.Join(entries, ", "))
+}
+
+// Given a change in steps, return it written with its sign
+func signedSteps(steps *big.Int) string {
+  if steps.Sign() >= 0 {
+    return "+" + steps.String()
+  }
+  return steps.String()
+}
+
+// Given the map of space from the first observation, the name of the file holding the
+// second, the expansion rates along each axis and a metric, print out everything that has
+// changed between the two. Rows and columns are only compared where both maps have them.
+func printUniverseDiff(beforeMap []string, filename string, xRate int, yRate int, metric string) error {
+  if metric != "manhattan" {
+    return fmt.Errorf("differences only support the manhattan metric, not %q", metric)
+  }
+
+  afterMap, err := readFile(filename)
+  if err != nil {
+    return err
+  }
+  if !expansionFits(spaceMapSize(afterMap), xRate) || !expansionFits(spaceMapSize(afterMap), yRate) {
+    return fmt.Errorf("expansion rate is too large to place galaxies in %v", filename)
+  }
+
+  beforeRows, beforeCols := findEmptySpace(beforeMap)
+  afterRows, afterCols := findEmptySpace(afterMap)
+  beforeGalaxies := extractGalaxies(beforeMap, xRate, yRate, beforeRows, beforeCols)
+  afterGalaxies := extractGalaxies(afterMap, xRate, yRate, afterRows, afterCols)
+
+  // Match up the galaxies from each map by their original position, keeping the total
+  // steps from each one in either map.
+  var changes []*GalaxyChange
+  var changeAt = make(map[[2]int]*GalaxyChange)
+  for _, observation := range []struct {
+    galaxies []map[string]int
+    isAfter bool
+  }{{beforeGalaxies, false}, {afterGalaxies, true}} {
+    sums := galaxyDistanceSums(observation.galaxies)
+    for idx, galaxy := range observation.galaxies {
+      position := [2]int{galaxy["ox"], galaxy["oy"]}
+      change, found := changeAt[position]
+      if !found {
+        change = &GalaxyChange{position: position, before: new(big.Int), after: new(big.Int)}
+        changeAt[position] = change
+        changes = append(changes, change)
+      }
+      if observation.isAfter {
+        change.after = sums[idx]
+      } else {
+        change.before = sums[idx]
+      }
+    }
+  }
+
+  var appeared, disappeared [][2]int
+  for _, change := range changes {
+    change.change = new(big.Int).Sub(change.after, change.before)
+    _, inBefore := slices.BinarySearchFunc(beforeGalaxies, change.position, comparePosition)
+    _, inAfter := slices.BinarySearchFunc(afterGalaxies, change.position, comparePosition)
+    switch {
+    case !inBefore:
+      appeared = append(appeared, change.position)
+    case !inAfter:
+      disappeared = append(disappeared, change.position)
+    }
+  }
+  slices.SortFunc(appeared, compareReadingOrder)
+  slices.SortFunc(disappeared, compareReadingOrder)
+  printDiffPositions("Appeared", appeared)
+  printDiffPositions("Disappeared", disappeared)
+
+  height := min(len(beforeMap), len(afterMap))
+  width := 0
+  if height > 0 {
+    width = min(len(beforeMap[0]), len(afterMap[0]))
+  }
+  printDiffLines("Rows now empty", linesOnlyIn(afterRows, beforeRows, height))
+  printDiffLines("Rows now occupied", linesOnlyIn(beforeRows, afterRows, height))
+  printDiffLines("Columns now empty", linesOnlyIn(afterCols, beforeCols, width))
+  printDiffLines("Columns now occupied", linesOnlyIn(beforeCols, afterCols, width))
+
+  beforeTotal := calculateStepsBetweenGalaxiesBig(beforeGalaxies)
+  afterTotal := calculateStepsBetweenGalaxiesBig(afterGalaxies)
+  fmt.Printf("Total: %v -> %v (%v)\n", beforeTotal, afterTotal, signedSteps(new(big.Int).Sub(afterTotal, beforeTotal)))
+
+  // The galaxies whose own steps changed the most come first, with ties in reading order.
+  slices.SortStableFunc(changes, func(a *GalaxyChange, b *GalaxyChange) int {
+    if order := new(big.Int).Abs(b.change).Cmp(new(big.Int).Abs(a.change)); order != 0 {
+      return order
+    }
+    return compareReadingOrder(a.position, b.position)
+  })
+  fmt.Println("Largest changes:")
+  for _, change := range changes[:min(DiffContributors, len(changes))] {
+    if change.change.Sign() == 0 {
+      break
+    }
+    fmt.Printf("(%v,%v): %v (%v -> %v)\n", change.position[0], change.position[1], signedSteps(change.change), change.before, change.after)
+  }
+  return nil
+}
+
+// Given a galaxy and an original position, compare them in reading order
+func comparePosition(galaxy map[string]int, position [2]int) int {
+  return compareReadingOrder([2]int{galaxy["ox"], galaxy["oy"]}, position)
+}
+
+// Given two original positions, compare them in reading order, from top to bottom and then
+// from left to right
+func compareReadingOrder(a [2]int, b [2]int) int {
+  if a[1] != b[1] {
+    return a[1] - b[1]
+  }
+  return a[0] - b[0]
+}
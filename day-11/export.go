@@ -0,0 +1,128 @@
+// Pair exports for day 11. Rather than only printing the total distance, every pair of
+// galaxies can be written out to a CSV or JSON file. Pairs are written as they are found,
+// so that we never need to keep millions of them in memory at once.
+package main
+
+import (
+  "bufio"
+  "encoding/csv"
+  "encoding/json"
+  "fmt"
+  "os"
+  "strconv"
+)
+
+// PairExport - A single pair of galaxies as it is written to a JSON export. Coordinates
+// are given as [x, y] pairs.
+type PairExport struct {
+  GalaxyA int `json:"galaxyA"`
+  GalaxyB int `json:"galaxyB"`
+  OriginalA [2]int `json:"originalA"`
+  OriginalB [2]int `json:"originalB"`
+  ExpandedA [2]int `json:"expandedA"`
+  ExpandedB [2]int `json:"expandedB"`
+  Distance json.Number `json:"distance"`
+}
+
+// Given a list of galaxies, the file to write to, the format of that file and the metric to
+// measure with, write out every pair of galaxies along with the distance between them.
+func exportGalaxyPairs(galaxyList []map[string]int, filename string, format string, metric string) error {
+  if _, err := distanceFunc(metric); err != nil {
+    return err
+  }
+  if format != "csv" && format != "json" {
+    return fmt.Errorf("unknown export format %q", format)
+  }
+
+  file, err := os.Create(filename)
+  if err != nil {
+    return err
+  }
+  defer file.Close()
+
+  writer := bufio.NewWriter(file)
+  if format == "csv" {
+    err = writePairsCsv(galaxyList, writer, metric)
+  } else {
+    err = writePairsJson(galaxyList, writer, metric)
+  }
+  if err != nil {
+    return err
+  }
+  return writer.Flush()
+}
+
+// Given a list of galaxies, a writer and the metric to measure with, write every pair of
+// galaxies out as a CSV row, starting with a header row.
+func writePairsCsv(galaxyList []map[string]int, writer *bufio.Writer, metric string) error {
+  csvWriter := csv.NewWriter(writer)
+  err := csvWriter.Write([]string{
+    "galaxy_a", "galaxy_b",
+    "original_a_x", "original_a_y", "original_b_x", "original_b_y",
+    "expanded_a_x", "expanded_a_y", "expanded_b_x", "expanded_b_y",
+    "distance",
+  })
+  if err != nil {
+    return err
+  }
+
+  for i := 0; i < len(galaxyList); i++ {
+    for j := i + 1; j < len(galaxyList); j++ {
+      galaxyA, galaxyB := galaxyList[i], galaxyList[j]
+      err = csvWriter.Write([]string{
+        strconv.Itoa(i + 1), strconv.Itoa(j + 1),
+        strconv.Itoa(galaxyA["ox"]), strconv.Itoa(galaxyA["oy"]),
+        strconv.Itoa(galaxyB["ox"]), strconv.Itoa(galaxyB["oy"]),
+        strconv.Itoa(galaxyA["x"]), strconv.Itoa(galaxyA["y"]),
+        strconv.Itoa(galaxyB["x"]), strconv.Itoa(galaxyB["y"]),
+        formatDistance(metric, galaxyA, galaxyB),
+      })
+      if err != nil {
+        return err
+      }
+    }
+  }
+
+  csvWriter.Flush()
+  return csvWriter.Error()
+}
+
+// Given a list of galaxies, a writer and the metric to measure with, write every pair of
+// galaxies out as an object within a JSON array. The array is written by hand around each
+// object so that it can be streamed out rather than built up in memory.
+func writePairsJson(galaxyList []map[string]int, writer *bufio.Writer, metric string) error {
+  if _, err := writer.WriteString("["); err != nil {
+    return err
+  }
+
+  separator := "\n"
+  for i := 0; i < len(galaxyList); i++ {
+    for j := i + 1; j < len(galaxyList); j++ {
+      galaxyA, galaxyB := galaxyList[i], galaxyList[j]
+
+      var pair PairExport
+      pair.GalaxyA = i + 1
+      pair.GalaxyB = j + 1
+      pair.OriginalA = [2]int{galaxyA["ox"], galaxyA["oy"]}
+      pair.OriginalB = [2]int{galaxyB["ox"], galaxyB["oy"]}
+      pair.ExpandedA = [2]int{galaxyA["x"], galaxyA["y"]}
+      pair.ExpandedB = [2]int{galaxyB["x"], galaxyB["y"]}
+      pair.Distance = json.Number(formatDistance(metric, galaxyA, galaxyB))
+
+      pairJson, err := json.Marshal(pair)
+      if err != nil {
+        return err
+      }
+      if _, err = writer.WriteString(separator); err != nil {
+        return err
+      }
+      if _, err = writer.Write(pairJson); err != nil {
+        return err
+      }
+      separator = ",\n"
+    }
+  }
+
+  _, err := writer.WriteString("\n]\n")
+  return err
+}
@@ -0,0 +1,41 @@
+// Fenwick trees for day 11. A Fenwick tree (or binary indexed tree) holds a list of counts,
+// and can both change a count and total up any prefix of the list in O(log n) time.
+package main
+
+// FenwickTree - A Fenwick tree over a fixed number of slots. Each entry in the tree holds
+// the total of a run of slots ending at that entry, with the length of the run being the
+// lowest set bit of its (1 based) index.
+type FenwickTree struct {
+  tree []int
+}
+
+// Given a number of slots, create a Fenwick tree with every slot at 0
+func newFenwickTree(size int) FenwickTree {
+  var fenwick FenwickTree
+  fenwick.tree = make([]int, size + 1)
+  return fenwick
+}
+
+// Given a slot and an amount, add that amount onto the slot
+func (fenwick FenwickTree) add(slot int, amount int) {
+  for idx := slot + 1; idx < len(fenwick.tree); idx += idx & -idx {
+    fenwick.tree[idx] += amount
+  }
+}
+
+// Given a number of slots, return the total of the first that many slots
+func (fenwick FenwickTree) prefix(count int) int {
+  total := 0
+  for idx := min(count, len(fenwick.tree) - 1); idx > 0; idx -= idx & -idx {
+    total += fenwick.tree[idx]
+  }
+  return total
+}
+
+// Given a range of slots from start up to (but not including) end, return their total
+func (fenwick FenwickTree) rangeSum(start int, end int) int {
+  if end <= start {
+    return 0
+  }
+  return fenwick.prefix(end) - fenwick.prefix(max(start, 0))
+}
@@ -0,0 +1,151 @@
+// Closed form answers for day 11. Every galaxy is pushed along by the expansion rate once
+// for every empty line before it, and this never changes the order of the galaxies along
+// either axis. That means the total steps between them grows by the same amount for every
+// extra step of expansion, so it can be written as base + perRate * rate.
+package main
+
+import (
+  "fmt"
+  "math/big"
+  "strconv"
+  "strings"
+)
+
+// ExpansionFormula - The total steps between every galaxy as a function of the expansion
+// rate. The base is the total with no expansion, and the X and Y parts are how much the
+// total grows for every step of expansion along that axis.
+type ExpansionFormula struct {
+  base *big.Int
+  perX *big.Int
+  perY *big.Int
+}
+
+// Given a function which expands the universe by a rate along each axis and returns the
+// galaxies within it, work out the formula for the total steps between every galaxy. As
+// the total is linear in each rate, it is enough to expand the universe with no growth,
+// and then with one step of growth along each axis.
+func findExpansionFormula(expand func(int, int) []map[string]int) ExpansionFormula {
+  var formula ExpansionFormula
+  formula.base = calculateStepsBetweenGalaxiesBig(expand(0, 0))
+
+  xTotal := calculateStepsBetweenGalaxiesBig(expand(1, 0))
+  formula.perX = xTotal.Sub(xTotal, formula.base)
+
+  yTotal := calculateStepsBetweenGalaxiesBig(expand(0, 1))
+  formula.perY = yTotal.Sub(yTotal, formula.base)
+  return formula
+}
+
+// Given the formula for a map, return how much the total grows for every step of
+// expansion when both axes expand at the same rate.
+func (formula ExpansionFormula) perRate() *big.Int {
+  return new(big.Int).Add(formula.perX, formula.perY)
+}
+
+// Given the formula for a map and an expansion rate along each axis, return the total
+// steps between every galaxy.
+func (formula ExpansionFormula) total(xRate *big.Int, yRate *big.Int) *big.Int {
+  total := new(big.Int).Set(formula.base)
+  total.Add(total, new(big.Int).Mul(formula.perX, xRate))
+  return total.Add(total, new(big.Int).Mul(formula.perY, yRate))
+}
+
+// Given a comma separated list of expansion rates, where each entry is either a single
+// rate or an inclusive range written as start..end, return the start and end of every
+// entry in the list. Ranges are kept as they are rather than listing out every rate within
+// them, as they can be far too large to hold.
+func parseRateList(rateList string) ([][2]int, error) {
+  var rates [][2]int
+  for _, entry := range strings.Split(rateList, ",") {
+    entry = strings.TrimSpace(entry)
+    if entry == "" {
+      continue
+    }
+
+    start, end, isRange := strings.Cut(entry, "..")
+    startRate, err := strconv.Atoi(start)
+    if err != nil {
+      return nil, fmt.Errorf("invalid expansion rate %q", entry)
+    }
+    endRate := startRate
+    if isRange {
+      endRate, err = strconv.Atoi(end)
+      if err != nil || endRate < startRate {
+        return nil, fmt.Errorf("invalid expansion rate range %q", entry)
+      }
+    }
+
+    rates = append(rates, [2]int{startRate, endRate})
+  }
+  return rates, nil
+}
+
+// Given the formula for a map and a target total, find the expansion rate which gives
+// that total when applied to both axes, and print it out. The rate is solved for exactly,
+// so a target which falls between two whole rates is reported as a fraction.
+func printTargetRate(formula ExpansionFormula, target *big.Int) {
+  perRate := formula.perRate()
+  remaining := new(big.Int).Sub(target, formula.base)
+
+  // With no empty lines to expand, the total never changes, so either every rate works
+  // or none of them do.
+  if perRate.Sign() == 0 {
+    if remaining.Sign() == 0 {
+      fmt.Printf("Every rate gives %v\n", target)
+    } else {
+      fmt.Printf("No rate gives %v, the total is always %v\n", target, formula.base)
+    }
+    return
+  }
+
+  // Empty lines can shrink down to nothing at a rate of -1 but no further, so that is
+  // where the smallest total is found.
+  rate := new(big.Rat).SetFrac(remaining, perRate)
+  if rate.Cmp(big.NewRat(-1, 1)) < 0 {
+    smallest := new(big.Int).Sub(formula.base, perRate)
+    fmt.Printf("No rate gives %v, the smallest total is %v\n", target, smallest)
+    return
+  }
+
+  fmt.Printf("Rate %v gives %v\n", rate.RatString(), target)
+}
+
+// Given a function which expands the universe, a list of rates and a target total, print
+// out the formula for the universe, followed by the total for each rate in the list, and
+// the rate which reaches the target (if one was given).
+func printExpansionFormula(expand func(int, int) []map[string]int, rateList string, target string) error {
+  rates, err := parseRateList(rateList)
+  if err != nil {
+    return err
+  }
+
+  var targetTotal *big.Int
+  if target != "" {
+    var valid bool
+    targetTotal, valid = new(big.Int).SetString(target, 10)
+    if !valid {
+      return fmt.Errorf("invalid target total %q", target)
+    }
+  }
+
+  formula := findExpansionFormula(expand)
+  fmt.Printf("%v + %v * rate\n", formula.base, formula.perRate())
+  debugLine(fmt.Sprintf("X grows by %v and Y grows by %v per rate", formula.perX, formula.perY))
+
+  // The end of a range is checked before moving on, so that a range which ends on the
+  // largest int doesn't wrap around.
+  for _, rateRange := range rates {
+    for rate := rateRange[0]; ; rate++ {
+      bigRate := big.NewInt(int64(rate))
+      fmt.Printf("%v: %v\n", rate, formula.total(bigRate, bigRate))
+      if rate == rateRange[1] {
+        break
+      }
+    }
+  }
+
+  if targetTotal != nil {
+    printTargetRate(formula, targetTotal)
+  }
+  return nil
+}
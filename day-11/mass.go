@@ -0,0 +1,98 @@
+// Weighted galaxies for day 11. Galaxies marked with the digits 1 to 9 have that mass,
+// while a # has a mass of 1. The distance between each pair of galaxies is weighted by the
+// mass of both, and the centre of mass is the point which is nearest to every galaxy once
+// their mass is taken into account.
+package main
+
+import (
+  "fmt"
+  "math/big"
+  "slices"
+)
+
+// Given a list of galaxies and the axis to look at, sum up the distance between every pair
+// of galaxies along that axis, multiplied by the mass of both galaxies. Once the galaxies
+// are sorted along the axis, a galaxy's share is its mass multiplied by its distance to
+// every earlier galaxy weighted by their mass, which is the total mass seen so far times
+// its position, minus the running total of mass times position. As masses multiply the
+// total, this is always counted with big integers.
+func sumWeightedAxisDistances(galaxyList []map[string]int, axis string) *big.Int {
+  sorted := slices.Clone(galaxyList)
+  slices.SortFunc(sorted, func(a map[string]int, b map[string]int) int {
+    return a[axis] - b[axis]
+  })
+
+  stepCount := new(big.Int)
+  massSeen := new(big.Int)
+  weightedSum := new(big.Int)
+  for _, galaxy := range sorted {
+    position := big.NewInt(int64(galaxy[axis]))
+    mass := big.NewInt(int64(galaxy["mass"]))
+
+    share := new(big.Int).Mul(massSeen, position)
+    share.Sub(share, weightedSum)
+    stepCount.Add(stepCount, share.Mul(share, mass))
+
+    massSeen.Add(massSeen, mass)
+    weightedSum.Add(weightedSum, new(big.Int).Mul(mass, position))
+  }
+  return stepCount
+}
+
+// Given a list of galaxies and the axis to look at, find the weighted median galaxy along
+// that axis. This is the first galaxy at which at least half of the total mass has been
+// seen, and no position along the axis has a smaller total distance to every galaxy
+// weighted by its mass.
+func weightedMedian(galaxyList []map[string]int, axis string) map[string]int {
+  sorted := slices.Clone(galaxyList)
+  slices.SortFunc(sorted, func(a map[string]int, b map[string]int) int {
+    return a[axis] - b[axis]
+  })
+
+  totalMass := 0
+  for _, galaxy := range sorted {
+    totalMass += galaxy["mass"]
+  }
+
+  massSeen := 0
+  for _, galaxy := range sorted {
+    massSeen += galaxy["mass"]
+    if 2 * massSeen >= totalMass {
+      return galaxy
+    }
+  }
+  return nil
+}
+
+// Given a list of galaxies and the metric to measure with, print out the total distance
+// between every pair of galaxies weighted by their mass, followed by the centre of mass in
+// both expanded and original coordinates and the weighted distance from it to every
+// galaxy. Only the manhattan metric can be split into its axes like this.
+func printWeightedDistances(galaxyList []map[string]int, metric string) error {
+  if metric != "manhattan" {
+    return fmt.Errorf("weighted distances can only be measured with the manhattan metric")
+  }
+
+  stepCount := sumWeightedAxisDistances(galaxyList, "x")
+  fmt.Println(stepCount.Add(stepCount, sumWeightedAxisDistances(galaxyList, "y")))
+  if len(galaxyList) == 0 {
+    return nil
+  }
+
+  // The manhattan distance splits into its axes, so the centre is the weighted median
+  // along each axis on its own. Each median sits on a galaxy, which gives us its original
+  // coordinate along that axis too.
+  xMedian := weightedMedian(galaxyList, "x")
+  yMedian := weightedMedian(galaxyList, "y")
+  var centre = map[string]int{"x": xMedian["x"], "y": yMedian["y"]}
+
+  centreSteps := new(big.Int)
+  for _, galaxy := range galaxyList {
+    steps := big.NewInt(int64(manhattanDistance(centre, galaxy)))
+    centreSteps.Add(centreSteps, steps.Mul(steps, big.NewInt(int64(galaxy["mass"]))))
+  }
+
+  fmt.Printf("Centre of mass (%v,%v), original (%v,%v), weighted distance %v\n",
+    centre["x"], centre["y"], xMedian["ox"], yMedian["oy"], centreSteps)
+  return nil
+}
@@ -0,0 +1,220 @@
+// Distance metrics for day 11. Beyond the manhattan distance used by the puzzle, we can
+// also measure the distance between galaxies as a king would move on a chess board
+// (chebyshev), or as the crow flies (euclidean).
+package main
+
+import (
+  "fmt"
+  "math"
+  "math/big"
+)
+
+// EuclideanPrecision - the number of decimal places to print a euclidean total with
+const EuclideanPrecision int = 6
+
+// Given a list of galaxies, the metric to measure with and whether to compare every pair
+// directly or use big integers, print out the total distance between every galaxy.
+func printGalaxyDistances(galaxyList []map[string]int, metric string, pairwise bool, forceBig bool) error {
+  total, err := totalGalaxyDistances(galaxyList, metric, pairwise, forceBig)
+  if err != nil {
+    return err
+  }
+  fmt.Println(total)
+  return nil
+}
+
+// Given a list of galaxies, the metric to measure with and whether to compare every pair
+// directly or use big integers, return the total distance between every galaxy ready to
+// be printed.
+func totalGalaxyDistances(galaxyList []map[string]int, metric string, pairwise bool, forceBig bool) (string, error) {
+  switch metric {
+  case "manhattan":
+    // The pairwise approach is far slower, but is useful for checking the sorted approach
+    // is right. If the total could be too large for an int, we move over to big integers.
+    if pairwise {
+      return fmt.Sprint(calculateStepsBetweenGalaxiesPairwise(galaxyList)), nil
+    } else if forceBig || stepsMayOverflow(galaxyList) {
+      debugLine("Total steps may overflow, counting with big integers")
+      return calculateStepsBetweenGalaxiesBig(galaxyList).String(), nil
+    }
+    return fmt.Sprint(calculateStepsBetweenGalaxies(galaxyList)), nil
+
+  case "chebyshev":
+    // A chebyshev distance is never larger than the manhattan distance between the same
+    // two galaxies, so the same overflow check holds here too.
+    if pairwise {
+      return fmt.Sprint(calculateChebyshevStepsBetweenGalaxiesPairwise(galaxyList)), nil
+    } else if forceBig || stepsMayOverflow(galaxyList) {
+      debugLine("Total steps may overflow, counting with big integers")
+      return calculateChebyshevStepsBetweenGalaxiesBig(galaxyList).String(), nil
+    }
+    return fmt.Sprint(calculateChebyshevStepsBetweenGalaxies(galaxyList)), nil
+
+  case "euclidean":
+    return fmt.Sprintf("%.*f", EuclideanPrecision, calculateEuclideanDistanceBetweenGalaxies(galaxyList)), nil
+  }
+  return "", fmt.Errorf("unknown distance metric %q", metric)
+}
+
+// Given a list of galaxies, rotate every coordinate by 45 degrees into u = x + y and
+// v = x - y. In this rotated space, the chebyshev distance between two galaxies is half
+// of the manhattan distance between them.
+func rotatedPositions(galaxyList []map[string]int) ([]int, []int) {
+  uPositions := make([]int, len(galaxyList))
+  vPositions := make([]int, len(galaxyList))
+  for idx, galaxy := range galaxyList {
+    uPositions[idx] = galaxy["x"] + galaxy["y"]
+    vPositions[idx] = galaxy["x"] - galaxy["y"]
+  }
+  return uPositions, vPositions
+}
+
+// Given a list of galaxies, calculate the number of king moves between every galaxy. This
+// is max(|dx|, |dy|) for each pair, which works out to be (|du| + |dv|) / 2 in the rotated
+// space, so we can total each rotated axis on its own in the same way as the manhattan
+// distance.
+func calculateChebyshevStepsBetweenGalaxies(galaxyList []map[string]int) int {
+  uPositions, vPositions := rotatedPositions(galaxyList)
+  uSteps := sumPairDifferences(uPositions)
+  vSteps := sumPairDifferences(vPositions)
+
+  // du and dv always share the same parity, so both totals are either odd or even. Halving
+  // them on their own avoids the two being added together past what fits into an int.
+  return uSteps / 2 + vSteps / 2 + uSteps % 2
+}
+
+// Given a list of galaxies, calculate the number of king moves between every galaxy in the
+// same way as calculateChebyshevStepsBetweenGalaxies, but using big integers.
+func calculateChebyshevStepsBetweenGalaxiesBig(galaxyList []map[string]int) *big.Int {
+  uPositions, vPositions := rotatedPositions(galaxyList)
+  stepCount := sumPairDifferencesBig(uPositions)
+  stepCount.Add(stepCount, sumPairDifferencesBig(vPositions))
+  return stepCount.Rsh(stepCount, 1)
+}
+
+// Given a list of galaxies, calculate the number of king moves between every galaxy by
+// comparing every pair in turn. This is used to cross-check the rotated approach, so the
+// total is always kept with big integers.
+func calculateChebyshevStepsBetweenGalaxiesPairwise(galaxyList []map[string]int) *big.Int {
+  stepCount := new(big.Int)
+  for i := 0; i < len(galaxyList); i++ {
+    for j := i + 1; j < len(galaxyList); j++ {
+      xSteps := galaxyList[i]["x"] - galaxyList[j]["x"]
+      ySteps := galaxyList[i]["y"] - galaxyList[j]["y"]
+      stepCount.Add(stepCount, big.NewInt(int64(max(xSteps, -xSteps, ySteps, -ySteps))))
+    }
+  }
+  return stepCount
+}
+
+// Given a list of galaxies, calculate the straight line distance between every galaxy.
+// Unlike the other metrics, there is no way to split this into its axes, so every pair
+// has to be visited. To keep rounding errors from building up over millions of pairs, we
+// carry a running compensation alongside the total (Neumaier summation).
+func calculateEuclideanDistanceBetweenGalaxies(galaxyList []map[string]int) float64 {
+  total := 0.0
+  compensation := 0.0
+  for i := 0; i < len(galaxyList); i++ {
+    for j := i + 1; j < len(galaxyList); j++ {
+      distance := math.Hypot(
+        float64(galaxyList[i]["x"] - galaxyList[j]["x"]),
+        float64(galaxyList[i]["y"] - galaxyList[j]["y"]))
+
+      nextTotal := total + distance
+      if math.Abs(total) >= distance {
+        compensation += (total - nextTotal) + distance
+      } else {
+        compensation += (distance - nextTotal) + total
+      }
+      total = nextTotal
+    }
+  }
+  return total + compensation
+}
+
+// Given two galaxies, return the number of steps up, down, left, or right between them.
+func manhattanDistance(galaxyA map[string]int, galaxyB map[string]int) int {
+  xSteps := galaxyA["x"] - galaxyB["x"]
+  ySteps := galaxyA["y"] - galaxyB["y"]
+  return max(xSteps, -xSteps) + max(ySteps, -ySteps)
+}
+
+// Given two galaxies, return the number of king moves between them.
+func chebyshevDistance(galaxyA map[string]int, galaxyB map[string]int) int {
+  xSteps := galaxyA["x"] - galaxyB["x"]
+  ySteps := galaxyA["y"] - galaxyB["y"]
+  return max(xSteps, -xSteps, ySteps, -ySteps)
+}
+
+// Given two galaxies, return the straight line distance between them.
+func euclideanDistance(galaxyA map[string]int, galaxyB map[string]int) float64 {
+  return math.Hypot(float64(galaxyA["x"] - galaxyB["x"]), float64(galaxyA["y"] - galaxyB["y"]))
+}
+
+// Given the name of a metric, return a function which measures the distance between two
+// galaxies under it. The distance is given as a float so that every metric can be compared
+// in the same way - use formatDistance to print the exact value.
+func distanceFunc(metric string) (func(map[string]int, map[string]int) float64, error) {
+  switch metric {
+  case "manhattan":
+    return func(galaxyA map[string]int, galaxyB map[string]int) float64 {
+      return float64(manhattanDistance(galaxyA, galaxyB))
+    }, nil
+  case "chebyshev":
+    return func(galaxyA map[string]int, galaxyB map[string]int) float64 {
+      return float64(chebyshevDistance(galaxyA, galaxyB))
+    }, nil
+  case "euclidean":
+    return euclideanDistance, nil
+  }
+  return nil, fmt.Errorf("unknown distance metric %q", metric)
+}
+
+// Given the name of a metric and two galaxies, return the distance between them ready to
+// be printed. This is exact for the step based metrics, and fixed to EuclideanPrecision
+// decimal places otherwise.
+func formatDistance(metric string, galaxyA map[string]int, galaxyB map[string]int) string {
+  switch metric {
+  case "manhattan":
+    return fmt.Sprint(manhattanDistance(galaxyA, galaxyB))
+  case "chebyshev":
+    return fmt.Sprint(chebyshevDistance(galaxyA, galaxyB))
+  }
+  return fmt.Sprintf("%.*f", EuclideanPrecision, euclideanDistance(galaxyA, galaxyB))
+}
+
+// Given the name of a metric, two galaxies and a distance limit, return whether the two
+// galaxies are no further apart than the limit. The step based metrics are compared
+// exactly.
+func withinDistance(metric string, galaxyA map[string]int, galaxyB map[string]int, limit int) bool {
+  switch metric {
+  case "manhattan":
+    return manhattanDistance(galaxyA, galaxyB) <= limit
+  case "chebyshev":
+    return chebyshevDistance(galaxyA, galaxyB) <= limit
+  }
+  return euclideanDistance(galaxyA, galaxyB) <= float64(limit)
+}
+
+// Given a list of galaxies, a list of links between pairs of them and the name of a metric,
+// return the total length of every link ready to be printed. The step based metrics are
+// totalled exactly.
+func totalEdgeDistance(galaxyList []map[string]int, edges [][2]int, metric string) string {
+  if metric == "euclidean" {
+    total := 0.0
+    for _, edge := range edges {
+      total += euclideanDistance(galaxyList[edge[0]], galaxyList[edge[1]])
+    }
+    return fmt.Sprintf("%.*f", EuclideanPrecision, total)
+  }
+
+  total := new(big.Int)
+  for _, edge := range edges {
+    if metric == "chebyshev" {
+      total.Add(total, big.NewInt(int64(chebyshevDistance(galaxyList[edge[0]], galaxyList[edge[1]]))))
+    } else {
+      total.Add(total, big.NewInt(int64(manhattanDistance(galaxyList[edge[0]], galaxyList[edge[1]]))))
+    }
+  }
+  return total.String()
+}
@@ -0,0 +1,137 @@
+// Nearest neighbour lookups for day 11. Rather than measuring every galaxy against every
+// other galaxy, the expanded galaxies are put into a k-d tree, which lets us skip over
+// whole regions of space that are further away than the neighbours we have already found.
+package main
+
+import (
+  "fmt"
+  "slices"
+  "strings"
+)
+
+// GalaxyTree - A node within a k-d tree of galaxies. Each node holds one galaxy, and
+// splits the galaxies beneath it along the given axis, with the galaxies at a lower
+// position on the left and the rest on the right.
+type GalaxyTree struct {
+  galaxy int
+  axis string
+  left *GalaxyTree
+  right *GalaxyTree
+}
+
+// Neighbour - A galaxy that has been found near to another, along with how far away it is
+type Neighbour struct {
+  galaxy int
+  distance float64
+}
+
+// Given a list of galaxies and the indexes of the galaxies to place into this part of the
+// tree, build up a k-d tree by splitting on the median galaxy, swapping between the X and
+// Y axis at each level.
+func buildGalaxyTree(galaxyList []map[string]int, indexes []int, depth int) *GalaxyTree {
+  if len(indexes) == 0 {
+    return nil
+  }
+
+  axis := "x"
+  if depth % 2 == 1 {
+    axis = "y"
+  }
+
+  slices.SortFunc(indexes, func(a int, b int) int {
+    return galaxyList[a][axis] - galaxyList[b][axis]
+  })
+  median := len(indexes) / 2
+
+  var node GalaxyTree
+  node.galaxy = indexes[median]
+  node.axis = axis
+  node.left = buildGalaxyTree(galaxyList, indexes[:median], depth + 1)
+  node.right = buildGalaxyTree(galaxyList, indexes[median + 1:], depth + 1)
+  return &node
+}
+
+// Given a list of neighbours which is sorted by distance, and a new candidate neighbour,
+// slot the candidate into place if it is nearer than any we have found so far, keeping at
+// most k neighbours. Ties are broken by the lowest galaxy number.
+func insertNeighbour(neighbours []Neighbour, candidate Neighbour, k int) []Neighbour {
+  insertAt := len(neighbours)
+  for insertAt > 0 {
+    previous := neighbours[insertAt - 1]
+    if previous.distance < candidate.distance ||
+      (previous.distance == candidate.distance && previous.galaxy < candidate.galaxy) {
+      break
+    }
+    insertAt--
+  }
+  if insertAt >= k {
+    return neighbours
+  }
+
+  neighbours = slices.Insert(neighbours, insertAt, candidate)
+  if len(neighbours) > k {
+    neighbours = neighbours[:k]
+  }
+  return neighbours
+}
+
+// Given a galaxy to search around, find the k galaxies within this tree which are nearest
+// to it under the given distance, adding them into the list of neighbours found so far.
+//
+// Every metric we support is at least as large as the gap along any one axis, so once the
+// gap to a node's splitting line is larger than the furthest neighbour we are holding on
+// to, nothing on the far side of that line can be any closer.
+func (tree *GalaxyTree) nearest(galaxyList []map[string]int, target int, k int, distance func(map[string]int, map[string]int) float64, neighbours []Neighbour) []Neighbour {
+  if tree == nil {
+    return neighbours
+  }
+
+  if tree.galaxy != target {
+    var candidate Neighbour
+    candidate.galaxy = tree.galaxy
+    candidate.distance = distance(galaxyList[target], galaxyList[tree.galaxy])
+    neighbours = insertNeighbour(neighbours, candidate, k)
+  }
+
+  // Search the side of the split that the target is on first, as that is where the
+  // nearest neighbours are most likely to be.
+  gap := galaxyList[target][tree.axis] - galaxyList[tree.galaxy][tree.axis]
+  nearSide, farSide := tree.right, tree.left
+  if gap < 0 {
+    nearSide, farSide = tree.left, tree.right
+  }
+
+  neighbours = nearSide.nearest(galaxyList, target, k, distance, neighbours)
+  if len(neighbours) < k || float64(max(gap, -gap)) <= neighbours[len(neighbours) - 1].distance {
+    neighbours = farSide.nearest(galaxyList, target, k, distance, neighbours)
+  }
+  return neighbours
+}
+
+// Given a list of galaxies, the number of neighbours to find and the metric to measure
+// with, print out the k nearest galaxies to every galaxy. Galaxies are numbered from 1
+// in the order they were read from the map, as in the puzzle.
+func printNearestNeighbours(galaxyList []map[string]int, k int, metric string) error {
+  distance, err := distanceFunc(metric)
+  if err != nil {
+    return err
+  }
+
+  indexes := make([]int, len(galaxyList))
+  for idx := range indexes {
+    indexes[idx] = idx
+  }
+  tree := buildGalaxyTree(galaxyList, indexes, 0)
+
+  for idx := range galaxyList {
+    neighbours := tree.nearest(galaxyList, idx, k, distance, nil)
+
+    var neighbourText []string
+    for _, neighbour := range neighbours {
+      neighbourText = append(neighbourText, fmt.Sprintf("%v (%v)", neighbour.galaxy + 1,
+        formatDistance(metric, galaxyList[idx], galaxyList[neighbour.galaxy])))
+    }
+    fmt.Printf("%v: %v\n", idx + 1, strings.Join(neighbourText, ", "))
+  }
+  return nil
+}
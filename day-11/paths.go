@@ -0,0 +1,186 @@
+// Shortest walking paths for day 11. When a map contains impassable matter, the steps
+// between two galaxies are no longer just the manhattan distance, so we have to search
+// for the real shortest path around anything that is in the way.
+//
+// The expanded grid is never built. An empty row that grows to h rows is made of h
+// identical copies of that row, so any path through it only ever needs to move sideways
+// along its first or last copy. We keep just those two copies of each expanded row and
+// column, and charge h - 1 steps to move straight between them.
+package main
+
+import (
+  "container/heap"
+  "fmt"
+  "math/big"
+  "slices"
+)
+
+// PathNode - A cell within the compressed grid, along with the steps taken to reach it
+type PathNode struct {
+  row int
+  col int
+  steps int
+}
+
+// PathQueue - A priority queue of cells, with the fewest steps taken at the front
+type PathQueue []PathNode
+
+func (queue PathQueue) Len() int { return len(queue) }
+func (queue PathQueue) Less(i, j int) bool { return queue[i].steps < queue[j].steps }
+func (queue PathQueue) Swap(i, j int) { queue[i], queue[j] = queue[j], queue[i] }
+func (queue *PathQueue) Push(node any) { *queue = append(*queue, node.(PathNode)) }
+func (queue *PathQueue) Pop() any {
+  old := *queue
+  node := old[len(old) - 1]
+  *queue = old[:len(old) - 1]
+  return node
+}
+
+// CompressedAxis - The lines along one axis of the compressed grid. Each entry holds the
+// original line that it is a copy of, and the steps needed to move onto it from the
+// entry before.
+type CompressedAxis struct {
+  original []int
+  stepsFrom []int
+}
+
+// Given the number of lines along an axis, the lines that are empty and the rate that
+// they expand by, build up the compressed lines for that axis. Empty lines which grow
+// to more than one line are given a first and last copy, with the rate as the steps
+// between the two.
+func compressAxis(lineCount int, emptyLines []int, expansionRate int) CompressedAxis {
+  var axis CompressedAxis
+  var isEmpty = make(map[int]bool)
+  for _, line := range emptyLines {
+    isEmpty[line] = true
+  }
+
+  for line := 0; line < lineCount; line++ {
+    axis.original = append(axis.original, line)
+    axis.stepsFrom = append(axis.stepsFrom, 1)
+    if isEmpty[line] && expansionRate > 0 {
+      axis.original = append(axis.original, line)
+      axis.stepsFrom = append(axis.stepsFrom, expansionRate)
+    }
+  }
+  return axis
+}
+
+// Given the compressed rows and columns and a galaxy, return the compressed row and
+// column that the galaxy sits in. Galaxies never sit on an empty line, so each one
+// maps onto exactly one compressed cell.
+func compressedCell(rows CompressedAxis, cols CompressedAxis, galaxy map[string]int) (int, int) {
+  row, _ := slices.BinarySearch(rows.original, galaxy["oy"])
+  col, _ := slices.BinarySearch(cols.original, galaxy["ox"])
+  return row, col
+}
+
+// Given the map of space, the compressed rows and columns, and a starting cell, find the
+// fewest steps needed to reach every cell in the compressed grid. Cells which cannot be
+// reached are left at -1.
+func walkFrom(spaceMap []string, rows CompressedAxis, cols CompressedAxis, startRow int, startCol int) [][]int {
+  var steps = make([][]int, len(rows.original))
+  for row := range steps {
+    steps[row] = make([]int, len(cols.original))
+    for col := range steps[row] {
+      steps[row][col] = -1
+    }
+  }
+
+  queue := &PathQueue{PathNode{row: startRow, col: startCol}}
+  for queue.Len() > 0 {
+    node := heap.Pop(queue).(PathNode)
+    if steps[node.row][node.col] != -1 {
+      continue
+    }
+    steps[node.row][node.col] = node.steps
+
+    // Moving onto the next line costs the steps recorded against that line, and moving
+    // back onto the previous line costs the steps recorded against the current one.
+    var moves = []PathNode{
+      {node.row - 1, node.col, node.steps},
+      {node.row + 1, node.col, node.steps},
+      {node.row, node.col - 1, node.steps},
+      {node.row, node.col + 1, node.steps},
+    }
+    for _, move := range moves {
+      if move.row < 0 || move.row >= len(rows.original) || move.col < 0 || move.col >= len(cols.original) {
+        continue
+      }
+      if spaceMap[rows.original[move.row]][cols.original[move.col]] == ObstacleSymbol {
+        continue
+      }
+      if steps[move.row][move.col] != -1 {
+        continue
+      }
+
+      switch {
+      case move.row > node.row:
+        move.steps += rows.stepsFrom[move.row]
+      case move.row < node.row:
+        move.steps += rows.stepsFrom[node.row]
+      case move.col > node.col:
+        move.steps += cols.stepsFrom[move.col]
+      default:
+        move.steps += cols.stepsFrom[node.col]
+      }
+      heap.Push(queue, move)
+    }
+  }
+  return steps
+}
+
+// Given the map of space and what is being done with it, return an error naming the first
+// row which is a different width to the first row, if there is one. Walking a map looks up
+// every cell by its row and column, so it can't be done when the rows are ragged.
+func checkRowWidths(spaceMap []string, action string) error {
+  for row, line := range spaceMap {
+    if len(line) != len(spaceMap[0]) {
+      return fmt.Errorf("%v needs every row of the map to be the same width, but row %v is %v wide rather than %v", action, row + 1, len(line), len(spaceMap[0]))
+    }
+  }
+  return nil
+}
+
+// Given the map of space, the galaxies within it, the expansion rates along each axis and
+// the empty rows and columns, walk the shortest path between every pair of galaxies and
+// print out the total steps taken. Any pairs which are walled off from each other are
+// counted separately.
+func printShortestPaths(spaceMap []string, galaxyList []map[string]int, xRate int, yRate int, xLines []int, yLines []int) error {
+  if xRate < 0 || yRate < 0 {
+    return fmt.Errorf("paths can only be walked with expansion rates of 0 or more")
+  }
+  if err := checkRowWidths(spaceMap, "walking paths"); err != nil {
+    return err
+  }
+  if len(spaceMap) == 0 {
+    fmt.Println(0)
+    return nil
+  }
+
+  rows := compressAxis(len(spaceMap), xLines, yRate)
+  cols := compressAxis(len(spaceMap[0]), yLines, xRate)
+
+  stepCount := new(big.Int)
+  unreachable := 0
+  for i := 0; i < len(galaxyList); i++ {
+    startRow, startCol := compressedCell(rows, cols, galaxyList[i])
+    steps := walkFrom(spaceMap, rows, cols, startRow, startCol)
+    for j := i + 1; j < len(galaxyList); j++ {
+      endRow, endCol := compressedCell(rows, cols, galaxyList[j])
+      pathSteps := steps[endRow][endCol]
+      if pathSteps == -1 {
+        unreachable++
+        continue
+      }
+      stepCount.Add(stepCount, big.NewInt(int64(pathSteps)))
+    }
+    debugLine(fmt.Sprintf("Walked all paths from galaxy %v", i + 1))
+  }
+
+  fmt.Println(stepCount)
+  if unreachable > 0 {
+    fmt.Printf("%v pairs of galaxies could not reach each other\n", unreachable)
+  }
+  return nil
+}
@@ -0,0 +1,209 @@
+// Expansion profiles for day 11. Rather than every empty line growing by the same rate, the
+// growth of each empty line can be looked up from a table, follow a geometric progression,
+// or change from one run of empty lines to the next. Galaxies are moved along by the
+// growth of every empty line before them, after which the totals are worked out in the
+// same way as for a single rate.
+package main
+
+import (
+  "bufio"
+  "fmt"
+  "math"
+  "math/big"
+  "os"
+  "slices"
+  "strconv"
+  "strings"
+)
+
+// ProfileEntry - A single line within a table profile, and how much it grows by
+type ProfileEntry struct {
+  line int
+  growth int
+}
+
+// ExpansionProfile - How the empty lines along each axis grow. The kind is one of table,
+// geometric or runs, and only the fields for that kind are filled in.
+type ExpansionProfile struct {
+  kind string
+  table map[string][]ProfileEntry
+  ratio int
+  runRates []int
+}
+
+// Given a profile written as table:file, geometric:ratio or runs:rate,rate,..., return the
+// profile that it describes.
+func parseExpansionProfile(spec string) (ExpansionProfile, error) {
+  var profile ExpansionProfile
+  kind, value, found := strings.Cut(spec, ":")
+  if !found || value == "" {
+    return profile, fmt.Errorf("invalid expansion profile %q", spec)
+  }
+  profile.kind = kind
+
+  switch kind {
+  case "table":
+    var err error
+    profile.table, err = readProfileTable(value)
+    if err != nil {
+      return profile, err
+    }
+  case "geometric":
+    ratio, err := strconv.Atoi(value)
+    if err != nil || ratio < 0 {
+      return profile, fmt.Errorf("invalid geometric ratio %q", value)
+    }
+    profile.ratio = ratio
+  case "runs":
+    for _, entry := range strings.Split(value, ",") {
+      rate, err := strconv.Atoi(strings.TrimSpace(entry))
+      if err != nil || rate < 0 {
+        return profile, fmt.Errorf("invalid run rate %q", entry)
+      }
+      profile.runRates = append(profile.runRates, rate)
+    }
+  default:
+    return profile, fmt.Errorf("unknown expansion profile %q", kind)
+  }
+  return profile, nil
+}
+
+// Given the name of a table file, return the growth of each line listed in it, sorted by
+// line along each axis. Each line of the file is written as "x|y line growth", where an
+// x line is a column and a y line is a row.
+func readProfileTable(filename string) (map[string][]ProfileEntry, error) {
+  file, err := os.Open(filename)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+
+  table := map[string][]ProfileEntry{}
+  scanner := bufio.NewScanner(file)
+  lineNumber := 0
+  for scanner.Scan() {
+    lineNumber++
+    fields := strings.Fields(scanner.Text())
+    if len(fields) == 0 {
+      continue
+    }
+    if len(fields) != 3 || (fields[0] != "x" && fields[0] != "y") {
+      return nil, fmt.Errorf("%v:%v: invalid table entry %q", filename, lineNumber, scanner.Text())
+    }
+    line, lineErr := strconv.Atoi(fields[1])
+    growth, growthErr := strconv.Atoi(fields[2])
+    if lineErr != nil || growthErr != nil || line < 0 || growth < 0 {
+      return nil, fmt.Errorf("%v:%v: invalid table entry %q", filename, lineNumber, scanner.Text())
+    }
+    table[fields[0]] = append(table[fields[0]], ProfileEntry{line, growth})
+  }
+  if err := scanner.Err(); err != nil {
+    return nil, err
+  }
+
+  for axis, entries := range table {
+    slices.SortFunc(entries, func(a ProfileEntry, b ProfileEntry) int {
+      return a.line - b.line
+    })
+    entries = slices.CompactFunc(entries, func(a ProfileEntry, b ProfileEntry) bool {
+      return a.line == b.line
+    })
+    table[axis] = entries
+  }
+  return table, nil
+}
+
+// Given an axis, the rate of expansion along it, and a run of empty lines that starts at
+// the given line, return how much the whole run grows by. The order is the number of empty
+// lines before the run, and the index is the number of runs before it.
+func (profile ExpansionProfile) runGrowth(axis string, expansionRate int, start int, length int, order int, index int) *big.Int {
+  switch profile.kind {
+  case "table":
+    // Every line grows by the rate, apart from those listed in the table.
+    growth := new(big.Int).Mul(big.NewInt(int64(expansionRate)), big.NewInt(int64(length)))
+    entries := profile.table[axis]
+    first, _ := slices.BinarySearchFunc(entries, start, func(entry ProfileEntry, line int) int {
+      return entry.line - line
+    })
+    for idx := first; idx < len(entries) && entries[idx].line < start + length; idx++ {
+      growth.Add(growth, big.NewInt(int64(entries[idx].growth - expansionRate)))
+    }
+    return growth
+
+  case "geometric":
+    // The nth empty line grows by rate * ratio^n, so the run is the sum of a geometric
+    // series. Past a few hundred lines any ratio above one is far too large to place, so
+    // the exponent is capped rather than working out an enormous number.
+    rate := big.NewInt(int64(expansionRate))
+    switch {
+    case expansionRate == 0:
+      return new(big.Int)
+    case profile.ratio == 0:
+      if order == 0 {
+        return rate
+      }
+      return new(big.Int)
+    case profile.ratio == 1:
+      return rate.Mul(rate, big.NewInt(int64(length)))
+    }
+    ratio := big.NewInt(int64(profile.ratio))
+    if order + length > 256 {
+      return new(big.Int).Lsh(big.NewInt(1), 256)
+    }
+    growth := new(big.Int).Exp(ratio, big.NewInt(int64(length)), nil)
+    growth.Sub(growth, big.NewInt(1))
+    growth.Div(growth, new(big.Int).Sub(ratio, big.NewInt(1)))
+    growth.Mul(growth, new(big.Int).Exp(ratio, big.NewInt(int64(order)), nil))
+    return growth.Mul(growth, rate)
+
+  default:
+    // Each run grows at its own rate, with the last rate carrying on for any runs left.
+    rate := profile.runRates[min(index, len(profile.runRates) - 1)]
+    return new(big.Int).Mul(big.NewInt(int64(rate)), big.NewInt(int64(length)))
+  }
+}
+
+// Given a list of galaxies, an axis, the original axis, the sorted list of occupied lines
+// along it and the rate of expansion along it, move every galaxy along by the growth of the
+// empty lines before it under the profile.
+func (profile ExpansionProfile) expandAxis(galaxyList []map[string]int, axis string, originalAxis string, occupied []int, expansionRate int) error {
+  offsets := make(map[int]int, len(occupied))
+  offset := new(big.Int)
+  limit := big.NewInt(math.MaxInt / 2)
+
+  previous := -1
+  runs := 0
+  for idx, line := range occupied {
+    // The lines between this occupied line and the last are a run of empty lines, with
+    // every line before the run that isn't occupied being empty.
+    if length := line - previous - 1; length > 0 {
+      offset.Add(offset, profile.runGrowth(axis, expansionRate, previous + 1, length, previous + 1 - idx, runs))
+      runs++
+      if new(big.Int).Add(offset, big.NewInt(int64(line))).Cmp(limit) > 0 {
+        return fmt.Errorf("expansion profile pushes galaxies too far along %v to place them", axis)
+      }
+    }
+    offsets[line] = int(offset.Int64())
+    previous = line
+  }
+
+  for _, galaxy := range galaxyList {
+    galaxy[axis] = galaxy[originalAxis] + offsets[galaxy[originalAxis]]
+  }
+  return nil
+}
+
+// Given a list of galaxies and the expansion rates for the X and Y axis, move every galaxy
+// to where the profile expands it to. Every profile falls back on the rates for some lines,
+// and a negative rate could shrink those lines past nothing, so the rates can't be negative.
+func (profile ExpansionProfile) apply(galaxyList []map[string]int, xRate int, yRate int) error {
+  if xRate < 0 || yRate < 0 {
+    return fmt.Errorf("expansion profiles can only be used with rates of 0 or more")
+  }
+  coordinates := originalCoordinates(galaxyList)
+  err := profile.expandAxis(galaxyList, "x", "ox", occupiedLines(coordinates, 0), xRate)
+  if err != nil {
+    return err
+  }
+  return profile.expandAxis(galaxyList, "y", "oy", occupiedLines(coordinates, 1), yRate)
+}
@@ -0,0 +1,70 @@
+// Fractional expansion for day 11. Expansion rates don't have to be whole numbers, so an
+// empty line might grow by half a line, or by two thirds of one. Rates between -1 and 0
+// shrink empty lines instead, down to nothing at all at -1. The order of the galaxies along
+// each axis never changes for any of these rates, so the total still follows the formula
+// base + perX * xRate + perY * yRate, which we can work out exactly with fractions.
+package main
+
+import (
+  "fmt"
+  "math/big"
+)
+
+// RationalPrecision - the number of decimal places to print fractional totals to
+const RationalPrecision int = 6
+
+// Given an expansion rate written as a whole number, a decimal or a fraction, return the
+// exact rate. Rates below -1 would shrink empty lines to less than nothing, so they are
+// rejected.
+func parseRate(rateText string) (*big.Rat, error) {
+  rate, valid := new(big.Rat).SetString(rateText)
+  if !valid {
+    return nil, fmt.Errorf("invalid expansion rate %q", rateText)
+  }
+  if rate.Cmp(big.NewRat(-1, 1)) < 0 {
+    return nil, fmt.Errorf("expansion rate %v would shrink empty lines to less than nothing", rateText)
+  }
+  return rate, nil
+}
+
+// Given an exact expansion rate, return it as an int, and whether it could be held as one
+func wholeRate(rate *big.Rat) (int, bool) {
+  if !rate.IsInt() || !rate.Num().IsInt64() {
+    return 0, false
+  }
+  return int(rate.Num().Int64()), true
+}
+
+// Given the formula for a map and an exact expansion rate along each axis, return the
+// exact total steps between every galaxy.
+func (formula ExpansionFormula) rationalTotal(xRate *big.Rat, yRate *big.Rat) *big.Rat {
+  total := new(big.Rat).SetInt(formula.base)
+  total.Add(total, new(big.Rat).Mul(new(big.Rat).SetInt(formula.perX), xRate))
+  return total.Add(total, new(big.Rat).Mul(new(big.Rat).SetInt(formula.perY), yRate))
+}
+
+// Given a function which expands the universe by a whole rate along each axis, the exact
+// rates along each axis and a metric, print out the exact total steps between every galaxy.
+// Only the manhattan metric is supported, as the formula only holds for it.
+func printRationalDistances(expand func(int, int) []map[string]int, xRate *big.Rat, yRate *big.Rat, metric string) error {
+  if metric != "manhattan" {
+    return fmt.Errorf("exact totals for fractional or very large rates only support the manhattan metric, not %q", metric)
+  }
+
+  formula := findExpansionFormula(expand)
+  debugLine(fmt.Sprintf("X grows by %v and Y grows by %v per rate", formula.perX, formula.perY))
+  printExactTotal(formula.rationalTotal(xRate, yRate), xRate, yRate)
+  return nil
+}
+
+// Given an exact total and the rates that gave it, print the total out as a fraction. When
+// any of the rates is a fraction, the same total is printed again as a decimal.
+func printExactTotal(total *big.Rat, rates ...*big.Rat) {
+  fmt.Println(total.RatString())
+  for _, rate := range rates {
+    if !rate.IsInt() {
+      fmt.Println(total.FloatString(RationalPrecision))
+      return
+    }
+  }
+}
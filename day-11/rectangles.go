@@ -0,0 +1,183 @@
+// Rectangle queries for day 11. Given a rectangle of expanded space and a point, we want to
+// know how many galaxies lie within the rectangle, and the total steps from the point to
+// each of them. The galaxies are held in a range tree, so that each query only has to look
+// at O(log^2 n) parts of the tree rather than at every galaxy.
+package main
+
+import (
+  "bufio"
+  "fmt"
+  "os"
+  "slices"
+  "strconv"
+  "strings"
+)
+
+// RangeTreeNode - A run of galaxies within a range tree, which are next to each other when
+// sorted along X. The galaxies are held sorted along Y, along with running totals of their
+// X and Y positions in that order.
+type RangeTreeNode struct {
+  yPositions []int
+  xSums []int
+  ySums []int
+}
+
+// GalaxyRangeTree - A range tree over a list of galaxies. The galaxies are sorted along X,
+// and each node of a segment tree over that order holds the galaxies beneath it sorted
+// along Y.
+type GalaxyRangeTree struct {
+  xPositions []int
+  nodes []RangeTreeNode
+}
+
+// RangeSummary - The number of galaxies within a rectangle, and the totals of their X and
+// Y positions
+type RangeSummary struct {
+  count int
+  xSum int
+  ySum int
+}
+
+// Given a list of galaxies, build up a range tree over them
+func buildGalaxyRangeTree(galaxyList []map[string]int) GalaxyRangeTree {
+  var tree GalaxyRangeTree
+  sorted := slices.Clone(galaxyList)
+  slices.SortFunc(sorted, func(a map[string]int, b map[string]int) int {
+    return a["x"] - b["x"]
+  })
+  tree.xPositions = axisPositions(sorted, "x")
+  tree.nodes = make([]RangeTreeNode, 4 * max(len(sorted), 1))
+  if len(sorted) > 0 {
+    tree.build(sorted, 1, 0, len(sorted))
+  }
+  return tree
+}
+
+// Given the galaxies sorted along X, a node and the run of galaxies it covers, fill in that
+// node and everything beneath it.
+func (tree GalaxyRangeTree) build(sorted []map[string]int, node int, start int, end int) {
+  run := slices.Clone(sorted[start:end])
+  slices.SortFunc(run, func(a map[string]int, b map[string]int) int {
+    return a["y"] - b["y"]
+  })
+
+  var treeNode RangeTreeNode
+  treeNode.yPositions = axisPositions(run, "y")
+  treeNode.xSums = make([]int, len(run) + 1)
+  treeNode.ySums = make([]int, len(run) + 1)
+  for idx, galaxy := range run {
+    treeNode.xSums[idx + 1] = treeNode.xSums[idx] + galaxy["x"]
+    treeNode.ySums[idx + 1] = treeNode.ySums[idx] + galaxy["y"]
+  }
+  tree.nodes[node] = treeNode
+
+  if end - start > 1 {
+    middle := (start + end) / 2
+    tree.build(sorted, 2 * node, start, middle)
+    tree.build(sorted, 2 * node + 1, middle, end)
+  }
+}
+
+// Given the corners of a rectangle (inclusive), return a summary of the galaxies within it
+func (tree GalaxyRangeTree) query(left int, top int, right int, bottom int) RangeSummary {
+  var summary RangeSummary
+  if left > right || top > bottom || len(tree.xPositions) == 0 {
+    return summary
+  }
+  start, _ := slices.BinarySearch(tree.xPositions, left)
+  end, _ := slices.BinarySearch(tree.xPositions, right + 1)
+  tree.collect(1, 0, len(tree.xPositions), start, end, top, bottom, &summary)
+  return summary
+}
+
+// Given a node and the run of galaxies it covers, add the galaxies from the run start to
+// end that lie between top and bottom into the summary. Nodes that sit entirely within
+// the run are answered from their Y order without going any further down the tree.
+func (tree GalaxyRangeTree) collect(node int, nodeStart int, nodeEnd int, start int, end int, top int, bottom int, summary *RangeSummary) {
+  if end <= nodeStart || nodeEnd <= start {
+    return
+  }
+  if start <= nodeStart && nodeEnd <= end {
+    treeNode := tree.nodes[node]
+    low, _ := slices.BinarySearch(treeNode.yPositions, top)
+    high, _ := slices.BinarySearch(treeNode.yPositions, bottom + 1)
+    summary.count += high - low
+    summary.xSum += treeNode.xSums[high] - treeNode.xSums[low]
+    summary.ySum += treeNode.ySums[high] - treeNode.ySums[low]
+    return
+  }
+  middle := (nodeStart + nodeEnd) / 2
+  tree.collect(2 * node, nodeStart, middle, start, end, top, bottom, summary)
+  tree.collect(2 * node + 1, middle, nodeEnd, start, end, top, bottom, summary)
+}
+
+// Given the corners of a rectangle (inclusive) and a point, return the number of galaxies
+// within the rectangle and the total steps from the point to each of them. The steps along
+// each axis are split into the galaxies before the point and those after it.
+func (tree GalaxyRangeTree) distanceTo(left int, top int, right int, bottom int, x int, y int) (int, int) {
+  before := tree.query(left, top, min(right, x - 1), bottom)
+  after := tree.query(max(left, x), top, right, bottom)
+  above := tree.query(left, top, right, min(bottom, y - 1))
+  below := tree.query(left, max(top, y), right, bottom)
+
+  steps := before.count * x - before.xSum + after.xSum - after.count * x
+  steps += above.count * y - above.ySum + below.ySum - below.count * y
+  return before.count + after.count, steps
+}
+
+// Given a list of galaxies, a file of queries and the expansion rates for the X and Y axis,
+// answer every query in the file. Each query is written as
+//
+//   expanded|original left top right bottom x y
+//
+// where the rectangle runs from left,top to right,bottom (inclusive) and x,y is the point
+// to measure from, all either in expanded or original coordinates. For each query, the
+// number of galaxies within the rectangle and the total steps from the point are printed.
+func printRectangleQueries(galaxyList []map[string]int, filename string, xRate int, yRate int) error {
+  file, err := os.Open(filename)
+  if err != nil {
+    return err
+  }
+  defer file.Close()
+
+  tree := buildGalaxyRangeTree(galaxyList)
+  coordinates := originalCoordinates(galaxyList)
+  xOccupied := occupiedLines(coordinates, 0)
+  yOccupied := occupiedLines(coordinates, 1)
+
+  scanner := bufio.NewScanner(file)
+  lineNumber := 0
+  for scanner.Scan() {
+    lineNumber++
+    fields := strings.Fields(scanner.Text())
+    if len(fields) == 0 {
+      continue
+    }
+    if len(fields) != 7 || (fields[0] != "expanded" && fields[0] != "original") {
+      return fmt.Errorf("%v:%v: invalid query %q", filename, lineNumber, scanner.Text())
+    }
+
+    var values [6]int
+    for idx := range values {
+      values[idx], err = strconv.Atoi(fields[idx + 1])
+      if err != nil {
+        return fmt.Errorf("%v:%v: invalid query %q", filename, lineNumber, scanner.Text())
+      }
+    }
+
+    // Original coordinates are moved into expanded space before the query is made. A
+    // rectangle edge in an empty line ends up at the start of that line's band, which
+    // holds no galaxies.
+    if fields[0] == "original" {
+      for idx := 0; idx < len(values); idx += 2 {
+        values[idx] = expandLine(xOccupied, xRate, values[idx])
+        values[idx + 1] = expandLine(yOccupied, yRate, values[idx + 1])
+      }
+    }
+
+    count, steps := tree.distanceTo(values[0], values[1], values[2], values[3], values[4], values[5])
+    debugLine(fmt.Sprintf("Query %v expanded to %v", fields, values))
+    fmt.Println(count, steps)
+  }
+  return scanner.Err()
+}
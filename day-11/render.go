@@ -0,0 +1,210 @@
+// Rendering for day 11. The expanded universe can be drawn out either as a text grid, as
+// in the puzzle, or as a PNG image. Galaxies are labelled with their number, and the empty
+// rows and columns that have been expanded are shaded so the expansion stands out.
+package main
+
+import (
+  "bufio"
+  "fmt"
+  "image"
+  "image/color"
+  "image/png"
+  "os"
+  "strconv"
+  "strings"
+)
+
+// MaxRenderCells - the largest number of expanded cells that we will try to draw
+const MaxRenderCells int = 1000000
+
+// RenderCellPixels - the width and height of a single expanded cell within a PNG render
+const RenderCellPixels int = 6
+
+// BandSymbol - the symbol used for empty space within an expanded row or column
+const BandSymbol byte = ':'
+
+// Palette indexes for each kind of cell within a PNG render
+const (
+  renderSpace uint8 = iota
+  renderBand
+  renderCrossing
+  renderObstacle
+  renderGalaxy
+  renderLabel
+)
+
+// renderPalette - the colours used for each kind of cell within a PNG render
+var renderPalette = color.Palette{
+  color.RGBA{0x10, 0x10, 0x18, 0xff},
+  color.RGBA{0x2a, 0x2a, 0x40, 0xff},
+  color.RGBA{0x3a, 0x3a, 0x58, 0xff},
+  color.RGBA{0x80, 0x80, 0x80, 0xff},
+  color.RGBA{0xff, 0xd8, 0x4a, 0xff},
+  color.RGBA{0xff, 0xff, 0xff, 0xff},
+}
+
+// digitGlyphs - a 3x5 pixel font for the digits 0 to 9, used to label galaxies in a PNG.
+// Each string is one row of the glyph, read from left to right.
+var digitGlyphs = [10][5]string{
+  {"###", "#.#", "#.#", "#.#", "###"},
+  {".#.", "##.", ".#.", ".#.", "###"},
+  {"###", "..#", "###", "#..", "###"},
+  {"###", "..#", "###", "..#", "###"},
+  {"#.#", "#.#", "###", "..#", "..#"},
+  {"###", "#..", "###", "..#", "###"},
+  {"###", "#..", "###", "#.#", "###"},
+  {"###", "..#", ".#.", ".#.", ".#."},
+  {"###", "#.#", "###", "#.#", "###"},
+  {"###", "#.#", "###", "..#", "###"},
+}
+
+// Given the number of lines along an axis, the lines that are empty and the rate that
+// they expand by, return the original line for every line of the expanded axis, along
+// with whether that line is part of an expanded band.
+func expandAxis(lineCount int, emptyLines []int, expansionRate int) ([]int, []bool) {
+  var isEmpty = make(map[int]bool)
+  for _, line := range emptyLines {
+    isEmpty[line] = true
+  }
+
+  var original []int
+  var inBand []bool
+  for line := 0; line < lineCount; line++ {
+    copies := 1
+    if isEmpty[line] {
+      copies += expansionRate
+    }
+    for copyIdx := 0; copyIdx < copies; copyIdx++ {
+      original = append(original, line)
+      inBand = append(inBand, isEmpty[line])
+    }
+  }
+  return original, inBand
+}
+
+// Given the map of space, the galaxies within it, the expansion rates along each axis, the
+// empty rows and columns and a file to write to, draw out the expanded universe. Files
+// ending in .png are drawn as an image, and anything else is written as a text grid.
+func renderUniverse(spaceMap []string, galaxyList []map[string]int, xRate int, yRate int, xLines []int, yLines []int, filename string) error {
+  if len(spaceMap) == 0 {
+    return fmt.Errorf("there is no map to render")
+  }
+  if xRate < 0 || yRate < 0 {
+    return fmt.Errorf("only expansion rates of 0 or more can be rendered")
+  }
+  if err := checkRowWidths(spaceMap, "rendering"); err != nil {
+    return err
+  }
+
+  // Check the size of the expanded universe before building anything, as a large rate can
+  // make it far too big to draw.
+  width, height := expandedSize(spaceMap, xRate, yRate, xLines, yLines)
+  if width > MaxRenderCells / height {
+    return fmt.Errorf("expanded universe of %vx%v is too large to render", width, height)
+  }
+
+  rows, rowBands := expandAxis(len(spaceMap), xLines, yRate)
+  cols, colBands := expandAxis(len(spaceMap[0]), yLines, xRate)
+
+  var galaxyAt = make(map[[2]int]int)
+  for idx, galaxy := range galaxyList {
+    galaxyAt[[2]int{galaxy["x"], galaxy["y"]}] = idx + 1
+  }
+
+  file, err := os.Create(filename)
+  if err != nil {
+    return err
+  }
+  defer file.Close()
+
+  if strings.HasSuffix(strings.ToLower(filename), ".png") {
+    return renderPng(spaceMap, galaxyAt, rows, rowBands, cols, colBands, file)
+  }
+  return renderText(spaceMap, galaxyAt, rows, rowBands, cols, colBands, file)
+}
+
+// Given the map of space, the galaxy numbers at each expanded coordinate, and the original
+// line and band for every expanded row and column, write the universe out as text. Every
+// cell is as wide as the largest galaxy number, so that each galaxy can be labelled in
+// full, and empty space within an expanded band is drawn with BandSymbol.
+func renderText(spaceMap []string, galaxyAt map[[2]int]int, rows []int, rowBands []bool, cols []int, colBands []bool, file *os.File) error {
+  cellWidth := len(strconv.Itoa(len(galaxyAt)))
+  writer := bufio.NewWriter(file)
+
+  for y, row := range rows {
+    for x, col := range cols {
+      if number, found := galaxyAt[[2]int{x, y}]; found {
+        label := strconv.Itoa(number)
+        writer.WriteString(strings.Repeat(".", cellWidth - len(label)) + label)
+        continue
+      }
+
+      symbol := spaceMap[row][col]
+      if symbol != ObstacleSymbol && (rowBands[y] || colBands[x]) {
+        symbol = BandSymbol
+      }
+      writer.WriteString(strings.Repeat(string(symbol), cellWidth))
+    }
+    writer.WriteString("\n")
+  }
+  return writer.Flush()
+}
+
+// Given the map of space, the galaxy numbers at each expanded coordinate, and the original
+// line and band for every expanded row and column, draw the universe out as a PNG. Each
+// cell is RenderCellPixels square, with expanded bands shaded and a lighter shade where
+// two bands cross. Galaxy labels are drawn once every cell has been filled in, so that
+// they sit on top of their neighbours.
+func renderPng(spaceMap []string, galaxyAt map[[2]int]int, rows []int, rowBands []bool, cols []int, colBands []bool, file *os.File) error {
+  img := image.NewPaletted(image.Rect(0, 0, len(cols) * RenderCellPixels, len(rows) * RenderCellPixels), renderPalette)
+
+  for y, row := range rows {
+    for x, col := range cols {
+      cell := renderSpace
+      switch {
+      case spaceMap[row][col] == ObstacleSymbol:
+        cell = renderObstacle
+      case rowBands[y] && colBands[x]:
+        cell = renderCrossing
+      case rowBands[y] || colBands[x]:
+        cell = renderBand
+      }
+      if _, found := galaxyAt[[2]int{x, y}]; found {
+        cell = renderGalaxy
+      }
+      fillCell(img, x, y, cell)
+    }
+  }
+
+  for position, number := range galaxyAt {
+    drawLabel(img, position[0], position[1], number)
+  }
+
+  return png.Encode(file, img)
+}
+
+// Given an image and an expanded cell, fill in that cell with the given palette colour
+func fillCell(img *image.Paletted, x int, y int, cell uint8) {
+  for py := y * RenderCellPixels; py < (y + 1) * RenderCellPixels; py++ {
+    for px := x * RenderCellPixels; px < (x + 1) * RenderCellPixels; px++ {
+      img.SetColorIndex(px, py, cell)
+    }
+  }
+}
+
+// Given an image and the expanded cell of a galaxy, draw the number of the galaxy just to
+// the right of it. Anything that would fall off the edge of the image is left out.
+func drawLabel(img *image.Paletted, x int, y int, number int) {
+  left := (x + 1) * RenderCellPixels + 1
+  top := y * RenderCellPixels
+  for digitIdx, digit := range strconv.Itoa(number) {
+    glyph := digitGlyphs[digit - '0']
+    for glyphY, glyphRow := range glyph {
+      for glyphX := range glyphRow {
+        if glyphRow[glyphX] == '#' {
+          img.SetColorIndex(left + digitIdx * 4 + glyphX, top + glyphY, renderLabel)
+        }
+      }
+    }
+  }
+}
@@ -0,0 +1,158 @@
+// Route planning for day 11. Rather than the distance between every pair of galaxies, we
+// look for the cheapest set of links which joins every galaxy together (the minimum
+// spanning tree), and a short round trip which visits every galaxy once (a tour).
+package main
+
+import (
+  "fmt"
+  "math"
+  "strings"
+)
+
+// Given a list of galaxies and a distance function, find the minimum spanning tree over
+// every galaxy using Prim's algorithm. The tree is grown out from the first galaxy, always
+// adding the galaxy which is nearest to anything already in the tree. Every galaxy can
+// link to every other, so keeping the nearest link for each galaxy in a plain slice is
+// quicker than a heap. The edges are returned in the order they were added.
+func minimumSpanningTree(galaxyList []map[string]int, distance func(map[string]int, map[string]int) float64) [][2]int {
+  var edges [][2]int
+  if len(galaxyList) == 0 {
+    return edges
+  }
+
+  var inTree = make([]bool, len(galaxyList))
+  var nearestDistance = make([]float64, len(galaxyList))
+  var nearestGalaxy = make([]int, len(galaxyList))
+  for idx := range nearestDistance {
+    nearestDistance[idx] = math.Inf(1)
+  }
+
+  current := 0
+  inTree[current] = true
+  for len(edges) < len(galaxyList) - 1 {
+    // Update the nearest link for every galaxy outside of the tree with the galaxy that
+    // was just added, and pick out the nearest of them all to add next.
+    next := -1
+    for idx := range galaxyList {
+      if inTree[idx] {
+        continue
+      }
+      linkDistance := distance(galaxyList[current], galaxyList[idx])
+      if linkDistance < nearestDistance[idx] {
+        nearestDistance[idx] = linkDistance
+        nearestGalaxy[idx] = current
+      }
+      if next == -1 || nearestDistance[idx] < nearestDistance[next] {
+        next = idx
+      }
+    }
+
+    inTree[next] = true
+    edges = append(edges, [2]int{nearestGalaxy[next], next})
+    current = next
+  }
+  return edges
+}
+
+// Given a list of galaxies and a distance function, build up a round trip which starts and
+// ends at the first galaxy, by always travelling to the nearest galaxy not yet visited.
+func nearestNeighbourTour(galaxyList []map[string]int, distance func(map[string]int, map[string]int) float64) []int {
+  var tour []int
+  if len(galaxyList) == 0 {
+    return tour
+  }
+
+  var visited = make([]bool, len(galaxyList))
+  current := 0
+  visited[current] = true
+  tour = append(tour, current)
+  for len(tour) < len(galaxyList) {
+    next := -1
+    nextDistance := 0.0
+    for idx := range galaxyList {
+      if visited[idx] {
+        continue
+      }
+      idxDistance := distance(galaxyList[current], galaxyList[idx])
+      if next == -1 || idxDistance < nextDistance {
+        next, nextDistance = idx, idxDistance
+      }
+    }
+    visited[next] = true
+    tour = append(tour, next)
+    current = next
+  }
+  return tour
+}
+
+// Given a list of galaxies, a round trip over them and a distance function, shorten the
+// trip with 2-opt moves. A move takes two legs of the trip, a->b and c->d, and swaps them
+// for a->c and b->d by reversing everything between b and c. Moves are made until none of
+// them make the trip any shorter.
+func improveTour(galaxyList []map[string]int, tour []int, distance func(map[string]int, map[string]int) float64) []int {
+  if len(tour) < 4 {
+    return tour
+  }
+
+  improved := true
+  for improved {
+    improved = false
+    for i := 0; i < len(tour) - 2; i++ {
+      for j := i + 2; j < len(tour); j++ {
+        a, b := galaxyList[tour[i]], galaxyList[tour[i + 1]]
+        c, d := galaxyList[tour[j]], galaxyList[tour[(j + 1) % len(tour)]]
+        if tour[i] == tour[(j + 1) % len(tour)] {
+          continue
+        }
+
+        // Allow for a little rounding in the euclidean distances, so that we don't keep
+        // swapping two legs that are really the same length.
+        change := distance(a, c) + distance(b, d) - distance(a, b) - distance(c, d)
+        if change < -1e-9 {
+          for left, right := i + 1, j; left < right; left, right = left + 1, right - 1 {
+            tour[left], tour[right] = tour[right], tour[left]
+          }
+          improved = true
+        }
+      }
+    }
+  }
+  return tour
+}
+
+// Given a list of galaxies, the metric to measure with and whether to find the minimum
+// spanning tree, a tour, or both, print out the total length of each along with its links
+// or the order the galaxies are visited in.
+func printGalaxyRoutes(galaxyList []map[string]int, metric string, spanningTree bool, tour bool) error {
+  distance, err := distanceFunc(metric)
+  if err != nil {
+    return err
+  }
+
+  if spanningTree {
+    edges := minimumSpanningTree(galaxyList, distance)
+    var edgeText []string
+    for _, edge := range edges {
+      edgeText = append(edgeText, fmt.Sprintf("%v-%v (%v)", edge[0] + 1, edge[1] + 1,
+        formatDistance(metric, galaxyList[edge[0]], galaxyList[edge[1]])))
+    }
+    fmt.Printf("Spanning tree %v\n", totalEdgeDistance(galaxyList, edges, metric))
+    fmt.Println(strings.Join(edgeText, ", "))
+  }
+
+  if tour {
+    visits := improveTour(galaxyList, nearestNeighbourTour(galaxyList, distance), distance)
+    var edges [][2]int
+    var visitText []string
+    for idx, galaxy := range visits {
+      edges = append(edges, [2]int{galaxy, visits[(idx + 1) % len(visits)]})
+      visitText = append(visitText, fmt.Sprint(galaxy + 1))
+    }
+    if len(visits) > 0 {
+      visitText = append(visitText, fmt.Sprint(visits[0] + 1))
+    }
+    fmt.Printf("Tour %v\n", totalEdgeDistance(galaxyList, edges, metric))
+    fmt.Println(strings.Join(visitText, " "))
+  }
+  return nil
+}
@@ -0,0 +1,230 @@
+// Sparse inputs for day 11. Rather than a map of space, galaxies can be given as a list of
+// x,y coordinates, one per line. Huge universes with only a few galaxies in them are then
+// cheap to work with, as the empty rows and columns are never listed out. Instead, the
+// number of empty lines before any coordinate is found from how many distinct occupied
+// lines come before it.
+package main
+
+import (
+  "bufio"
+  "fmt"
+  "os"
+  "regexp"
+  "slices"
+  "strconv"
+  "strings"
+)
+
+// SparseLineCheck - the measure of whether a line of a sparse input is a valid coordinate
+const SparseLineCheck string = `^\s*(?P<X>[0-9]+)\s*,\s*(?P<Y>[0-9]+)\s*$`
+
+// Given a filename, look at the first line with anything on it to work out whether the file
+// holds a map of space (dense) or a list of coordinates (sparse). A volume looks just like a
+// map with blank lines in it, so volumes are never picked up here and have to be asked for.
+// In strict mode, a map which carries on after a blank line is rejected as ambiguous rather
+// than guessing which of the two was meant.
+func detectInputFormat(filename string) (string, error) {
+  file, err := os.Open(filename)
+  if err != nil {
+    return "", err
+  }
+  defer file.Close()
+
+  var lineRegex = regexp.MustCompile(SparseLineCheck)
+
+  scanner := bufio.NewScanner(file)
+  lineNumber := 0
+  for scanner.Scan() {
+    lineNumber++
+    var line = scanner.Text()
+    if strings.TrimSpace(line) == "" {
+      continue
+    }
+    if lineRegex.MatchString(line) {
+      return "sparse", nil
+    }
+    if strict {
+      return "dense", checkSingleMap(filename, lineNumber, scanner)
+    }
+    return "dense", nil
+  }
+  return "dense", scanner.Err()
+}
+
+// Given the name of a file and a scanner part way through a map of space in it, along with
+// the number of the line it is on, look through the rest of the file and return an error
+// pointing at the first line of the map which comes after a blank line, if there is one.
+func checkSingleMap(filename string, lineNumber int, scanner *bufio.Scanner) error {
+  sawBlank := false
+  for scanner.Scan() {
+    lineNumber++
+    if strings.TrimSpace(scanner.Text()) == "" {
+      sawBlank = true
+    } else if sawBlank {
+      return fmt.Errorf("%v:%v:1: map carries on after a blank line, use -inputfmt volume to read it as layers or -inputfmt dense to skip the blank lines", filename, lineNumber)
+    }
+  }
+  return scanner.Err()
+}
+
+// Given the name of a file, the number of a line within it and the line itself, return an
+// error pointing at the first place where the line stops looking like an x,y coordinate, if
+// there is one. Line numbers and columns both count from 1.
+func checkCoordinateLine(filename string, lineNumber int, line string) error {
+  col := 0
+  skipSpace := func() {
+    for col < len(line) && strings.IndexByte(" \t\n\f\r", line[col]) >= 0 {
+      col++
+    }
+  }
+  skipDigits := func() bool {
+    start := col
+    for col < len(line) && line[col] >= '0' && line[col] <= '9' {
+      col++
+    }
+    return col > start
+  }
+
+  skipSpace()
+  if skipDigits() {
+    skipSpace()
+    if col < len(line) && line[col] == ',' {
+      col++
+      skipSpace()
+      if skipDigits() {
+        skipSpace()
+        if col == len(line) {
+          return nil
+        }
+      }
+    }
+  }
+  return fmt.Errorf("%v:%v:%v: invalid coordinate %q", filename, lineNumber, col + 1, line)
+}
+
+// Read in a given file of coordinates and return each one as an [x, y] pair. The
+// coordinates are sorted into reading order, so that galaxies are numbered in the same
+// way as they would be in a map of space. In strict mode, any line which isn't a
+// coordinate is an error, while otherwise it is skipped over. Blank lines are always
+// skipped.
+func readCoordinates(filename string) ([][2]int, error) {
+  var coordinates [][2]int
+
+  file, err := os.Open(filename)
+  if err != nil {
+    return coordinates, err
+  }
+  defer file.Close()
+
+  var lineRegex = regexp.MustCompile(SparseLineCheck)
+  xIndex := lineRegex.SubexpIndex("X")
+  yIndex := lineRegex.SubexpIndex("Y")
+
+  scanner := bufio.NewScanner(file)
+  lineNumber := 0
+  for scanner.Scan() {
+    lineNumber++
+    var line = scanner.Text()
+    if strict && strings.TrimSpace(line) != "" {
+      if err := checkCoordinateLine(filename, lineNumber, line); err != nil {
+        return coordinates, err
+      }
+    }
+    var matches = lineRegex.FindStringSubmatchIndex(line)
+    if matches == nil {
+      continue
+    }
+
+    // Each number is read in from where its group starts and ends, so that a number too
+    // large to hold can be pointed at in the same way as any other invalid coordinate.
+    var coordinate [2]int
+    for axis, groupIndex := range []int{xIndex, yIndex} {
+      start, end := matches[2 * groupIndex], matches[2 * groupIndex + 1]
+      coordinate[axis], err = strconv.Atoi(line[start:end])
+      if err != nil {
+        return coordinates, fmt.Errorf("%v:%v:%v: coordinate %v is too large", filename, lineNumber, start + 1, line[start:end])
+      }
+    }
+    coordinates = append(coordinates, coordinate)
+  }
+
+  slices.SortFunc(coordinates, func(a [2]int, b [2]int) int {
+    if a[1] != b[1] {
+      return a[1] - b[1]
+    }
+    return a[0] - b[0]
+  })
+  return coordinates, scanner.Err()
+}
+
+// Given a list of coordinates and the axis to look at (0 for X, 1 for Y), return every
+// distinct position along that axis which holds a galaxy, in order.
+func occupiedLines(coordinates [][2]int, axis int) []int {
+  var lines []int
+  for _, coordinate := range coordinates {
+    lines = append(lines, coordinate[axis])
+  }
+  slices.Sort(lines)
+  return slices.Compact(lines)
+}
+
+// Given a list of galaxies, return the original coordinate of each one as an [x, y] pair
+func originalCoordinates(galaxyList []map[string]int) [][2]int {
+  var coordinates [][2]int
+  for _, galaxy := range galaxyList {
+    coordinates = append(coordinates, [2]int{galaxy["ox"], galaxy["oy"]})
+  }
+  return coordinates
+}
+
+// Given the sorted list of occupied lines along an axis, the expansion rate and an original
+// line, return where that line ends up once the universe has expanded. Every line before
+// it which isn't occupied is empty, so the number of empty lines before it is its position
+// less the occupied lines before it, and each of those pushes it along by the rate.
+func expandLine(occupied []int, expansionRate int, line int) int {
+  before, _ := slices.BinarySearch(occupied, line)
+  return line + (line - before) * expansionRate
+}
+
+// Given a list of coordinates, return the size of the longest side of the universe that
+// holds them, which runs from 0 up to the largest coordinate.
+func coordinatesSize(coordinates [][2]int) int {
+  mapSize := 0
+  for _, coordinate := range coordinates {
+    mapSize = max(mapSize, coordinate[0] + 1, coordinate[1] + 1)
+  }
+  return mapSize
+}
+
+// Given a list of coordinates and the expansion rates for the X and Y axis, return every
+// galaxy along with its original and expanded position, in the same way as
+// extractGalaxies.
+func extractSparseGalaxies(coordinates [][2]int, xRate int, yRate int) []map[string]int {
+  var galaxyList []map[string]int
+  xOccupied := occupiedLines(coordinates, 0)
+  yOccupied := occupiedLines(coordinates, 1)
+
+  for _, coordinate := range coordinates {
+    tmpMap := make(map[string]int)
+    tmpMap["ox"] = coordinate[0]
+    tmpMap["oy"] = coordinate[1]
+    tmpMap["x"] = expandLine(xOccupied, xRate, coordinate[0])
+    tmpMap["y"] = expandLine(yOccupied, yRate, coordinate[1])
+    tmpMap["mass"] = 1
+    galaxyList = append(galaxyList, tmpMap)
+  }
+  return galaxyList
+}
+
+// Given a list of coordinates and the expansion rates for the X and Y axis, return the
+// width and height of the universe once it has expanded.
+func sparseExpandedSize(coordinates [][2]int, xRate int, yRate int) (int, int) {
+  width, height := 0, 0
+  for _, coordinate := range coordinates {
+    width = max(width, coordinate[0] + 1)
+    height = max(height, coordinate[1] + 1)
+  }
+  width += (width - len(occupiedLines(coordinates, 0))) * xRate
+  height += (height - len(occupiedLines(coordinates, 1))) * yRate
+  return width, height
+}
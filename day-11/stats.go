@@ -0,0 +1,187 @@
+// Distance statistics for day 11. Beyond the total, we report how the distances between
+// every pair of galaxies are spread out, without ever listing out the pairs themselves.
+//
+// Rotating every galaxy into u = x + y and v = x - y turns the manhattan distance into the
+// larger of |du| and |dv|. That makes the furthest pair easy to find, and means that the
+// pairs within a distance of each other are those within a square in the rotated space,
+// which we can count with a sweep along u and a Fenwick tree over v.
+package main
+
+import (
+  "fmt"
+  "math/big"
+  "slices"
+  "strings"
+)
+
+// StatsPercentiles - the percentiles reported alongside the other statistics
+var StatsPercentiles = []int{10, 25, 50, 75, 90, 99}
+
+// StatsMeanPrecision - the number of decimal places to print the mean distance with
+const StatsMeanPrecision int = 6
+
+// StatsHistogramBins - the number of bins to split the distances into for the histogram
+const StatsHistogramBins int = 10
+
+// StatsHistogramWidth - the length of the bar for the fullest bin of the histogram
+const StatsHistogramWidth int = 40
+
+// PairCounter - Counts the pairs of galaxies within a distance of each other. The galaxies
+// are held in their rotated coordinates, sorted along u, with the rank of each v position
+// amongst every distinct v position.
+type PairCounter struct {
+  uPositions []int
+  vPositions []int
+  vSorted []int
+}
+
+// Given a list of galaxies, build up a counter for the pairs between them
+func newPairCounter(galaxyList []map[string]int) PairCounter {
+  var counter PairCounter
+  uPositions, vPositions := rotatedPositions(galaxyList)
+
+  order := make([]int, len(galaxyList))
+  for idx := range order {
+    order[idx] = idx
+  }
+  slices.SortFunc(order, func(a int, b int) int {
+    return uPositions[a] - uPositions[b]
+  })
+  for _, idx := range order {
+    counter.uPositions = append(counter.uPositions, uPositions[idx])
+    counter.vPositions = append(counter.vPositions, vPositions[idx])
+  }
+
+  counter.vSorted = slices.Clone(vPositions)
+  slices.Sort(counter.vSorted)
+  counter.vSorted = slices.Compact(counter.vSorted)
+  return counter
+}
+
+// Given a distance limit, count the pairs of galaxies which are no further apart than the
+// limit. We sweep along u, keeping every galaxy within the limit behind the current one
+// in a Fenwick tree by the rank of its v position, then count how many of those are also
+// within the limit along v.
+func (counter PairCounter) countWithin(limit int) int {
+  if limit < 0 {
+    return 0
+  }
+
+  fenwick := newFenwickTree(len(counter.vSorted))
+  pairCount := 0
+  behind := 0
+  for idx, uPosition := range counter.uPositions {
+    for uPosition - counter.uPositions[behind] > limit {
+      rank, _ := slices.BinarySearch(counter.vSorted, counter.vPositions[behind])
+      fenwick.add(rank, -1)
+      behind++
+    }
+
+    vPosition := counter.vPositions[idx]
+    low, _ := slices.BinarySearch(counter.vSorted, vPosition - limit)
+    high, found := slices.BinarySearch(counter.vSorted, vPosition + limit)
+    if found {
+      high++
+    }
+    pairCount += fenwick.rangeSum(low, high)
+
+    rank, _ := slices.BinarySearch(counter.vSorted, vPosition)
+    fenwick.add(rank, 1)
+  }
+  return pairCount
+}
+
+// Given a target number of pairs and the smallest and largest distances, find the smallest
+// distance which has at least that many pairs within it.
+func (counter PairCounter) distanceHolding(pairTarget int, low int, high int) int {
+  for low < high {
+    middle := low + (high - low) / 2
+    if counter.countWithin(middle) >= pairTarget {
+      high = middle
+    } else {
+      low = middle + 1
+    }
+  }
+  return low
+}
+
+// Given a list of galaxies, find the furthest pair apart. In the rotated space this is the
+// larger of the spread along u and the spread along v.
+func maxGalaxyDistance(galaxyList []map[string]int) int {
+  uPositions, vPositions := rotatedPositions(galaxyList)
+  return max(slices.Max(uPositions) - slices.Min(uPositions), slices.Max(vPositions) - slices.Min(vPositions))
+}
+
+// Given a list of galaxies, find the closest pair together by looking up the nearest
+// neighbour of every galaxy within a k-d tree.
+func minGalaxyDistance(galaxyList []map[string]int) int {
+  indexes := make([]int, len(galaxyList))
+  for idx := range indexes {
+    indexes[idx] = idx
+  }
+  tree := buildGalaxyTree(galaxyList, indexes, 0)
+  distance, _ := distanceFunc("manhattan")
+
+  minDistance := -1
+  for idx := range galaxyList {
+    neighbours := tree.nearest(galaxyList, idx, 1, distance, nil)
+    nearest := manhattanDistance(galaxyList[idx], galaxyList[neighbours[0].galaxy])
+    if minDistance == -1 || nearest < minDistance {
+      minDistance = nearest
+    }
+  }
+  return minDistance
+}
+
+// Given a list of galaxies and the metric to measure with, print out statistics over the
+// distance between every pair of galaxies, followed by a histogram of the distances.
+// Percentiles use the nearest rank, so the median is the distance of the middle pair (or
+// the lower of the two middle pairs).
+func printDistanceStats(galaxyList []map[string]int, metric string) error {
+  if metric != "manhattan" {
+    return fmt.Errorf("distance statistics can only be measured with the manhattan metric")
+  }
+  if len(galaxyList) < 2 {
+    return fmt.Errorf("at least two galaxies are needed for distance statistics")
+  }
+
+  pairCount := len(galaxyList) * (len(galaxyList) - 1) / 2
+  total := calculateStepsBetweenGalaxiesBig(galaxyList)
+  mean := new(big.Rat).SetFrac(total, big.NewInt(int64(pairCount)))
+  minDistance := minGalaxyDistance(galaxyList)
+  maxDistance := maxGalaxyDistance(galaxyList)
+  counter := newPairCounter(galaxyList)
+
+  fmt.Printf("Pairs %v\n", pairCount)
+  fmt.Printf("Total %v\n", total)
+  fmt.Printf("Min %v\n", minDistance)
+  fmt.Printf("Max %v\n", maxDistance)
+  fmt.Printf("Mean %v\n", mean.FloatString(StatsMeanPrecision))
+  fmt.Printf("Median %v\n", counter.distanceHolding((pairCount + 1) / 2, minDistance, maxDistance))
+  for _, percentile := range StatsPercentiles {
+    pairTarget := (pairCount * percentile + 99) / 100
+    fmt.Printf("P%v %v\n", percentile, counter.distanceHolding(pairTarget, minDistance, maxDistance))
+  }
+
+  // Split the distances into bins of equal width, and count the pairs within each bin from
+  // the pairs within its upper and lower edges.
+  binWidth := (maxDistance - minDistance) / StatsHistogramBins + 1
+  var binCounts []int
+  var binLabels []string
+  for binStart := minDistance; binStart <= maxDistance; binStart += binWidth {
+    binEnd := min(binStart + binWidth - 1, maxDistance)
+    binCounts = append(binCounts, counter.countWithin(binEnd) - counter.countWithin(binStart - 1))
+    binLabels = append(binLabels, fmt.Sprintf("%v-%v", binStart, binEnd))
+  }
+
+  labelWidth := 0
+  for _, label := range binLabels {
+    labelWidth = max(labelWidth, len(label))
+  }
+  fullest := slices.Max(binCounts)
+  for idx, binCount := range binCounts {
+    bar := strings.Repeat("#", binCount * StatsHistogramWidth / fullest)
+    fmt.Printf("%*v | %v %v\n", labelWidth, binLabels[idx], bar, binCount)
+  }
+  return nil
+}
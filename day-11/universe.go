@@ -0,0 +1,380 @@
+// Dynamic universes for day 11. A Universe holds a set of galaxies which can be added to
+// or taken away from one at a time, while keeping the total steps between every pair of
+// galaxies up to date, rather than working it all out again after every change.
+//
+// Along each axis, a galaxy at position o which has c occupied lines before it ends up at
+// o + rate * (o - c) once the universe has expanded. Both o and c grow together, so the
+// distance between two galaxies along that axis is (1 + rate) * |do| - rate * |dc|. We
+// keep the total of |do| and the total of |dc| over every pair, and move each of them by
+// however much a single galaxy changes it.
+package main
+
+import (
+  "bufio"
+  "fmt"
+  "math/big"
+  "os"
+  "slices"
+  "strconv"
+  "strings"
+)
+
+// RankNode - A summary of a run of positions along an axis. It holds the number of
+// galaxies within the run, the number of occupied lines within it, and the number of
+// pairs of an occupied line and a galaxy where the line comes before the galaxy.
+type RankNode struct {
+  galaxies int
+  lines int
+  crossings int
+}
+
+// Given the summaries of two runs of positions, where the first comes directly before the
+// second, return the summary of both runs together. Every occupied line in the first run
+// comes before every galaxy in the second run.
+func mergeRankNodes(before RankNode, after RankNode) RankNode {
+  var merged RankNode
+  merged.galaxies = before.galaxies + after.galaxies
+  merged.lines = before.lines + after.lines
+  merged.crossings = before.crossings + after.crossings + before.lines * after.galaxies
+  return merged
+}
+
+// RankTree - A segment tree of RankNode summaries over the positions along an axis. The
+// crossings of a run starting from the first position is the sum of the number of
+// occupied lines before every galaxy within it, which is what the |dc| total is built
+// from. A Fenwick tree can't hold this, as a line becoming occupied changes the count for
+// every galaxy after it by a different amount, so the summaries are merged in order instead.
+type RankTree struct {
+  size int
+  nodes []RankNode
+}
+
+// Given a number of positions, create a rank tree with nothing in it
+func newRankTree(positionCount int) RankTree {
+  var tree RankTree
+  tree.size = 1
+  for tree.size < positionCount {
+    tree.size *= 2
+  }
+  tree.nodes = make([]RankNode, 2 * tree.size)
+  return tree
+}
+
+// Given a position and the number of galaxies now at it, update the summary of every run
+// which holds that position.
+func (tree RankTree) set(position int, galaxyCount int) {
+  node := tree.size + position
+  tree.nodes[node].galaxies = galaxyCount
+  tree.nodes[node].lines = min(galaxyCount, 1)
+  for node /= 2; node > 0; node /= 2 {
+    tree.nodes[node] = mergeRankNodes(tree.nodes[2 * node], tree.nodes[2 * node + 1])
+  }
+}
+
+// Given a number of positions, return the summary of that many positions from the start,
+// by merging the runs that make them up from left to right.
+func (tree RankTree) prefix(count int) RankNode {
+  var summary RankNode
+  var rightRuns []RankNode
+  low, high := tree.size, tree.size + min(count, tree.size)
+  for low < high {
+    if low % 2 == 1 {
+      summary = mergeRankNodes(summary, tree.nodes[low])
+      low++
+    }
+    if high % 2 == 1 {
+      high--
+      rightRuns = append(rightRuns, tree.nodes[high])
+    }
+    low /= 2
+    high /= 2
+  }
+  for idx := len(rightRuns) - 1; idx >= 0; idx-- {
+    summary = mergeRankNodes(summary, rightRuns[idx])
+  }
+  return summary
+}
+
+// UniverseAxis - The galaxies along one axis of a universe. Positions are the distinct
+// coordinates along the axis that can hold a galaxy, and the Fenwick trees track the
+// number of galaxies at each position and the sum of their coordinates. The totals of
+// |do| and |dc| over every pair of galaxies are kept up to date as galaxies come and go.
+type UniverseAxis struct {
+  positions []int
+  counts FenwickTree
+  sums FenwickTree
+  ranks RankTree
+  galaxiesAt []int
+  positionTotal *big.Int
+  rankTotal *big.Int
+}
+
+// Given every coordinate that a galaxy could be placed at along an axis, create an axis
+// with no galaxies on it.
+func newUniverseAxis(coordinates []int) UniverseAxis {
+  var axis UniverseAxis
+  axis.positions = slices.Clone(coordinates)
+  slices.Sort(axis.positions)
+  axis.positions = slices.Compact(axis.positions)
+  axis.counts = newFenwickTree(len(axis.positions))
+  axis.sums = newFenwickTree(len(axis.positions))
+  axis.ranks = newRankTree(len(axis.positions))
+  axis.galaxiesAt = make([]int, len(axis.positions))
+  axis.positionTotal = new(big.Int)
+  axis.rankTotal = new(big.Int)
+  return axis
+}
+
+// Given a position, return the total |do| and |dc| between a galaxy at that position and
+// every other galaxy on the axis. Galaxies sharing the position are 0 apart.
+func (axis UniverseAxis) distancesFrom(position int) (int, int) {
+  coordinate := axis.positions[position]
+  below := axis.counts.prefix(position)
+  above := axis.counts.rangeSum(position + 1, len(axis.positions))
+  belowSum := axis.sums.prefix(position)
+  aboveSum := axis.sums.rangeSum(position + 1, len(axis.positions))
+  positionSteps := below * coordinate - belowSum + aboveSum - above * coordinate
+
+  // The rank of a galaxy is the number of occupied lines before it, and the crossings of
+  // a run from the start is the sum of the ranks of the galaxies within it.
+  throughPosition := axis.ranks.prefix(position + 1)
+  rank := throughPosition.lines - min(axis.galaxiesAt[position], 1)
+  belowRanks := axis.ranks.prefix(position).crossings
+  aboveRanks := axis.ranks.prefix(len(axis.positions)).crossings - throughPosition.crossings
+  rankSteps := below * rank - belowRanks + aboveRanks - above * rank
+  return positionSteps, rankSteps
+}
+
+// Given a position, return the number of galaxies strictly before and after it
+func (axis UniverseAxis) galaxiesAround(position int) (int, int) {
+  return axis.counts.prefix(position), axis.counts.rangeSum(position + 1, len(axis.positions))
+}
+
+// Given a coordinate, return where it sits along the axis. An error is returned if the
+// coordinate wasn't given when the axis was created, as there is no slot to hold it.
+func (axis UniverseAxis) position(coordinate int) (int, error) {
+  position, found := slices.BinarySearch(axis.positions, coordinate)
+  if !found {
+    return 0, fmt.Errorf("%v was not given when the universe was created", coordinate)
+  }
+  return position, nil
+}
+
+// Given a coordinate, add a galaxy at it. If the line was empty before, every galaxy after
+// it has one more occupied line before it, which pulls each of them one step closer (in
+// rank) to every galaxy before the line.
+func (axis UniverseAxis) add(coordinate int) error {
+  position, err := axis.position(coordinate)
+  if err != nil {
+    return err
+  }
+  newLine := axis.galaxiesAt[position] == 0
+
+  positionSteps, _ := axis.distancesFrom(position)
+  axis.positionTotal.Add(axis.positionTotal, big.NewInt(int64(positionSteps)))
+
+  axis.galaxiesAt[position]++
+  axis.ranks.set(position, axis.galaxiesAt[position])
+  if newLine {
+    below, above := axis.galaxiesAround(position)
+    axis.rankTotal.Add(axis.rankTotal, big.NewInt(int64(below * above)))
+  }
+  _, rankSteps := axis.distancesFrom(position)
+  axis.rankTotal.Add(axis.rankTotal, big.NewInt(int64(rankSteps)))
+
+  axis.counts.add(position, 1)
+  axis.sums.add(position, coordinate)
+  return nil
+}
+
+// Given a coordinate, remove a galaxy from it. This undoes the steps of add in reverse.
+func (axis UniverseAxis) remove(coordinate int) error {
+  position, err := axis.position(coordinate)
+  if err != nil {
+    return err
+  }
+  axis.counts.add(position, -1)
+  axis.sums.add(position, -coordinate)
+
+  positionSteps, rankSteps := axis.distancesFrom(position)
+  axis.positionTotal.Sub(axis.positionTotal, big.NewInt(int64(positionSteps)))
+  axis.rankTotal.Sub(axis.rankTotal, big.NewInt(int64(rankSteps)))
+
+  axis.galaxiesAt[position]--
+  axis.ranks.set(position, axis.galaxiesAt[position])
+  if axis.galaxiesAt[position] == 0 {
+    below, above := axis.galaxiesAround(position)
+    axis.rankTotal.Sub(axis.rankTotal, big.NewInt(int64(below * above)))
+  }
+  return nil
+}
+
+// Given the expansion rate for the axis, return the total steps along it between every
+// pair of galaxies, which is (1 + rate) * |do| - rate * |dc| summed over every pair.
+func (axis UniverseAxis) total(expansionRate int) *big.Int {
+  rate := big.NewInt(int64(expansionRate))
+  total := new(big.Int).Mul(axis.positionTotal, new(big.Int).Add(rate, big.NewInt(1)))
+  return total.Sub(total, new(big.Int).Mul(axis.rankTotal, rate))
+}
+
+// Universe - A set of galaxies that can change over time, with the total steps between
+// every pair of them kept up to date. Each add or remove takes O(log n) time. The Fenwick
+// trees along each axis have a slot for every coordinate given when the universe is created
+// and can't grow, so every coordinate that could ever hold a galaxy has to be known up
+// front, and adding a galaxy anywhere else is an error.
+type Universe struct {
+  xAxis UniverseAxis
+  yAxis UniverseAxis
+  xRate int
+  yRate int
+  galaxies map[[2]int]int
+}
+
+// Given every coordinate that could hold a galaxy and the expansion rates for the X and
+// Y axis, create an empty universe.
+func newUniverse(coordinates [][2]int, xRate int, yRate int) Universe {
+  var universe Universe
+  var xCoordinates, yCoordinates []int
+  for _, coordinate := range coordinates {
+    xCoordinates = append(xCoordinates, coordinate[0])
+    yCoordinates = append(yCoordinates, coordinate[1])
+  }
+  universe.xAxis = newUniverseAxis(xCoordinates)
+  universe.yAxis = newUniverseAxis(yCoordinates)
+  universe.xRate = xRate
+  universe.yRate = yRate
+  universe.galaxies = make(map[[2]int]int)
+  return universe
+}
+
+// Given a coordinate, add a galaxy to the universe at it. An error is returned if the
+// coordinate wasn't given when the universe was created, before anything is changed.
+func (universe Universe) add(coordinate [2]int) error {
+  for _, check := range []struct {
+    axis UniverseAxis
+    coordinate int
+  }{{universe.xAxis, coordinate[0]}, {universe.yAxis, coordinate[1]}} {
+    if _, err := check.axis.position(check.coordinate); err != nil {
+      return fmt.Errorf("%v,%v can't hold a galaxy: %v", coordinate[0], coordinate[1], err)
+    }
+  }
+  if err := universe.xAxis.add(coordinate[0]); err != nil {
+    return err
+  }
+  if err := universe.yAxis.add(coordinate[1]); err != nil {
+    return err
+  }
+  universe.galaxies[coordinate]++
+  return nil
+}
+
+// Given a coordinate, remove a galaxy from the universe at it. An error is returned if
+// there is no galaxy there to remove.
+func (universe Universe) remove(coordinate [2]int) error {
+  if universe.galaxies[coordinate] == 0 {
+    return fmt.Errorf("there is no galaxy at %v,%v to remove", coordinate[0], coordinate[1])
+  }
+  if err := universe.xAxis.remove(coordinate[0]); err != nil {
+    return err
+  }
+  if err := universe.yAxis.remove(coordinate[1]); err != nil {
+    return err
+  }
+  universe.galaxies[coordinate]--
+  return nil
+}
+
+// Return the total steps between every pair of galaxies in the universe
+func (universe Universe) total() *big.Int {
+  total := universe.xAxis.total(universe.xRate)
+  return total.Add(total, universe.yAxis.total(universe.yRate))
+}
+
+// UniverseCommand - A single line of a universe script, which is either add, del or total,
+// along with the coordinate to add or delete.
+type UniverseCommand struct {
+  line int
+  action string
+  coordinate [2]int
+}
+
+// Read in a given universe script, with one command on each line. Blank lines are skipped.
+func readUniverseScript(filename string) ([]UniverseCommand, error) {
+  var commands []UniverseCommand
+
+  file, err := os.Open(filename)
+  if err != nil {
+    return commands, err
+  }
+  defer file.Close()
+
+  scanner := bufio.NewScanner(file)
+  lineNumber := 0
+  for scanner.Scan() {
+    lineNumber++
+    fields := strings.Fields(scanner.Text())
+    if len(fields) == 0 {
+      continue
+    }
+
+    var command UniverseCommand
+    command.line = lineNumber
+    command.action = fields[0]
+    switch {
+    case command.action == "total" && len(fields) == 1:
+    case (command.action == "add" || command.action == "del") && len(fields) == 3:
+      x, xErr := strconv.Atoi(fields[1])
+      y, yErr := strconv.Atoi(fields[2])
+      if xErr != nil || yErr != nil || x < 0 || y < 0 {
+        return commands, fmt.Errorf("%v:%v: invalid coordinate in %q", filename, lineNumber, scanner.Text())
+      }
+      command.coordinate = [2]int{x, y}
+    default:
+      return commands, fmt.Errorf("%v:%v: unknown command %q", filename, lineNumber, scanner.Text())
+    }
+    commands = append(commands, command)
+  }
+  return commands, scanner.Err()
+}
+
+// Given the galaxies to start with, a universe script and the expansion rates for the X
+// and Y axis, run every command within the script against a universe, printing the total
+// steps between every galaxy whenever the script asks for it. The universe is built to
+// hold every coordinate the script mentions, so the whole script is read in first.
+func runUniverseScript(galaxyList []map[string]int, filename string, xRate int, yRate int) error {
+  commands, err := readUniverseScript(filename)
+  if err != nil {
+    return err
+  }
+
+  coordinates := originalCoordinates(galaxyList)
+  for _, command := range commands {
+    if command.action != "total" {
+      coordinates = append(coordinates, command.coordinate)
+    }
+  }
+
+  universe := newUniverse(coordinates, xRate, yRate)
+  for _, galaxy := range galaxyList {
+    if err = universe.add([2]int{galaxy["ox"], galaxy["oy"]}); err != nil {
+      return err
+    }
+  }
+
+  for _, command := range commands {
+    switch command.action {
+    case "add":
+      if err = universe.add(command.coordinate); err != nil {
+        return fmt.Errorf("%v:%v: %v", filename, command.line, err)
+      }
+    case "del":
+      if err = universe.remove(command.coordinate); err != nil {
+        return fmt.Errorf("%v:%v: %v", filename, command.line, err)
+      }
+    case "total":
+      fmt.Println(universe.total())
+    }
+    debugLine(fmt.Sprintf("%v %v leaves a total of %v", command.action, command.coordinate, universe.total()))
+  }
+  return nil
+}
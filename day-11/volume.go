@@ -0,0 +1,220 @@
+// Volumes for day 11. A volume is several maps of space one after the other, separated by
+// blank lines, where each map is a layer along the Z axis. Any plane of the volume which
+// doesn't hold a galaxy expands, in the same way as an empty row or column does in a
+// single map, and the steps between galaxies are counted along all three axes.
+package main
+
+import (
+  "bufio"
+  "fmt"
+  "math/big"
+  "os"
+  "regexp"
+  "strings"
+)
+
+// Read in a given file as a volume, returning each layer of the volume as a slice of lines.
+// Layers are separated by one or more blank lines. As with readFile, strict mode rejects
+// unexpected symbols, along with any line of any layer that is a different width to the
+// first line of the volume, and any layer that is a different height to the first layer.
+func readVolume(filename string) ([][]string, error) {
+  var layers [][]string
+
+  file, err := os.Open(filename)
+  if err != nil {
+    return layers, err
+  }
+  defer file.Close()
+
+  var lineRegex = regexp.MustCompile(ValidLineCheck)
+
+  scanner := bufio.NewScanner(file)
+  var layer []string
+  lineNumber := 0
+  width := -1
+
+  // A layer which runs short of the first is only found once it ends, so it is reported
+  // on the line where the next row was expected.
+  endLayer := func(endLine int) error {
+    if strict && len(layers) > 0 && len(layer) != len(layers[0]) {
+      return fmt.Errorf("%v:%v:1: layer %v is %v tall rather than %v", filename, endLine, len(layers) + 1, len(layer), len(layers[0]))
+    }
+    layers = append(layers, layer)
+    layer = nil
+    return nil
+  }
+
+  for scanner.Scan() {
+    lineNumber++
+    var line = scanner.Text()
+    if strict {
+      line = strings.TrimSuffix(line, "\r")
+    }
+    if strings.TrimSpace(line) == "" {
+      if len(layer) > 0 {
+        if err := endLayer(lineNumber); err != nil {
+          return layers, err
+        }
+      }
+      continue
+    }
+    if strict {
+      if width == -1 {
+        width = len(line)
+      }
+      if err := checkMapLine(filename, lineNumber, line, width); err != nil {
+        return layers, err
+      }
+      if len(layers) > 0 && len(layer) == len(layers[0]) {
+        return layers, fmt.Errorf("%v:%v:1: layer %v is taller than %v", filename, lineNumber, len(layers) + 1, len(layers[0]))
+      }
+      layer = append(layer, line)
+    } else if lineRegex.MatchString(line) {
+      layer = append(layer, line)
+    }
+  }
+  if len(layer) > 0 {
+    if err := endLayer(lineNumber + 1); err != nil {
+      return layers, err
+    }
+  }
+  return layers, scanner.Err()
+}
+
+// Given the layers of a volume, return which X, Y and Z planes hold at least one galaxy.
+// The volume is as wide as the widest line and as tall as the tallest layer.
+func findOccupiedPlanes(layers [][]string) ([]bool, []bool, []bool) {
+  var width, height int
+  for _, layer := range layers {
+    height = max(height, len(layer))
+    for _, line := range layer {
+      width = max(width, len(line))
+    }
+  }
+
+  xOccupied := make([]bool, width)
+  yOccupied := make([]bool, height)
+  zOccupied := make([]bool, len(layers))
+  for z, layer := range layers {
+    for y, line := range layer {
+      for x := 0; x < len(line); x++ {
+        if isGalaxy(line[x]) {
+          xOccupied[x] = true
+          yOccupied[y] = true
+          zOccupied[z] = true
+        }
+      }
+    }
+  }
+  return xOccupied, yOccupied, zOccupied
+}
+
+// Given which planes along an axis are occupied and the expansion rate along it, return
+// where each plane ends up once the empty planes before it have expanded.
+func expandPlanes(occupied []bool, expansionRate int) []int {
+  positions := make([]int, len(occupied))
+  offset := 0
+  for idx, isOccupied := range occupied {
+    positions[idx] = idx + offset
+    if !isOccupied {
+      offset += expansionRate
+    }
+  }
+  return positions
+}
+
+// Given the layers of a volume and the expansion rates for the X, Y and Z axis, return a
+// map of all galaxies within the volume, along with their original and expanded positions
+// and their mass.
+func extractVolumeGalaxies(layers [][]string, xRate int, yRate int, zRate int) []map[string]int {
+  var galaxyList []map[string]int
+
+  xOccupied, yOccupied, zOccupied := findOccupiedPlanes(layers)
+  xPositions := expandPlanes(xOccupied, xRate)
+  yPositions := expandPlanes(yOccupied, yRate)
+  zPositions := expandPlanes(zOccupied, zRate)
+
+  for z, layer := range layers {
+    for y, line := range layer {
+      for x := 0; x < len(line); x++ {
+        if !isGalaxy(line[x]) {
+          continue
+        }
+        galaxyList = append(galaxyList, map[string]int{
+          "ox": x,
+          "oy": y,
+          "oz": z,
+          "x": xPositions[x],
+          "y": yPositions[y],
+          "z": zPositions[z],
+          "mass": galaxyMass(line[x]),
+        })
+      }
+    }
+  }
+  return galaxyList
+}
+
+// Given the layers of a volume, return the size of its longest side
+func volumeSize(layers [][]string) int {
+  xOccupied, yOccupied, zOccupied := findOccupiedPlanes(layers)
+  return max(len(xOccupied), len(yOccupied), len(zOccupied))
+}
+
+// Given a list of galaxies within a volume, return the total steps between every pair of
+// galaxies by comparing each pair directly.
+func calculateVolumeStepsPairwise(galaxyList []map[string]int) *big.Int {
+  total := new(big.Int)
+  for i := 0; i < len(galaxyList); i++ {
+    for j := i + 1; j < len(galaxyList); j++ {
+      for _, axis := range []string{"x", "y", "z"} {
+        total.Add(total, big.NewInt(int64(max(galaxyList[i][axis] - galaxyList[j][axis], galaxyList[j][axis] - galaxyList[i][axis]))))
+      }
+    }
+  }
+  return total
+}
+
+// Given a list of galaxies within a volume, return the total steps between every pair of
+// galaxies. As with a single map, the steps along each axis can be totalled on their own.
+func calculateVolumeSteps(galaxyList []map[string]int) *big.Int {
+  total := new(big.Int)
+  for _, axis := range []string{"x", "y", "z"} {
+    total.Add(total, sumAxisDistancesBig(galaxyList, axis))
+  }
+  return total
+}
+
+// Given a list of galaxies within a volume and a metric, print out the total steps between
+// every pair of galaxies. Only the manhattan metric is supported for volumes.
+func printVolumeDistances(galaxyList []map[string]int, metric string, pairwise bool) error {
+  if metric != "manhattan" {
+    return fmt.Errorf("volumes only support the manhattan metric, not %q", metric)
+  }
+  if pairwise {
+    fmt.Println(calculateVolumeStepsPairwise(galaxyList))
+  } else {
+    fmt.Println(calculateVolumeSteps(galaxyList))
+  }
+  return nil
+}
+
+// Given the layers of a volume, the exact expansion rates along each axis and a metric,
+// print out the exact total steps between every galaxy. As with a single map, the total
+// grows by the same amount for every step of expansion along each axis, so it is enough to
+// expand the volume with no growth, and then with one step of growth along each axis.
+func printRationalVolumeDistances(layers [][]string, rates [3]*big.Rat, metric string) error {
+  if metric != "manhattan" {
+    return fmt.Errorf("volumes only support the manhattan metric, not %q", metric)
+  }
+
+  base := calculateVolumeSteps(extractVolumeGalaxies(layers, 0, 0, 0))
+  total := new(big.Rat).SetInt(base)
+  for axis, growth := range [][3]int{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}} {
+    perRate := calculateVolumeSteps(extractVolumeGalaxies(layers, growth[0], growth[1], growth[2]))
+    perRate.Sub(perRate, base)
+    total.Add(total, new(big.Rat).Mul(new(big.Rat).SetInt(perRate), rates[axis]))
+  }
+  printExactTotal(total, rates[:]...)
+  return nil
+}
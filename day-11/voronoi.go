@@ -0,0 +1,355 @@
+// Territories for day 11. Every cell of the expanded universe is claimed by the galaxy that
+// can walk to it in the fewest steps, splitting the universe up into one region per galaxy.
+// Cells which more than one galaxy can reach in the fewest steps are tied, and cells which
+// no galaxy can reach at all are left unclaimed.
+//
+// As with the shortest paths, the expanded grid is never built. All of the galaxies walk
+// out over the compressed grid together, which gives the steps to the first and last copy
+// of every expanded line. The copies in between are identical, so the steps to any of them
+// are the fewest steps to either end of the band plus the walk along it.
+package main
+
+import (
+  "bufio"
+  "container/heap"
+  "fmt"
+  "math"
+  "os"
+  "strconv"
+  "strings"
+)
+
+// TieSymbol - the symbol used for cells which are tied between galaxies in a territory map
+const TieSymbol byte = '+'
+
+// MaxTerritoryRows - the largest number of rows that we will walk through where expanded
+// rows and columns cross when counting up territories
+const MaxTerritoryRows int = 100000000
+
+// Owners for cells which don't belong to a single galaxy
+const (
+  ownerNone int = -1
+  ownerTied int = -2
+)
+
+// Territory - The fewest steps needed to reach a cell, and the galaxy which can reach it in
+// those steps. Cells which can't be reached have steps of -1.
+type Territory struct {
+  steps int
+  owner int
+}
+
+// TerritoryLocator - Where an expanded line sits within the compressed grid. Lines which
+// are kept in the compressed grid have an offset of 0, while lines between two kept lines
+// are offset from the first of them.
+type TerritoryLocator struct {
+  index int
+  offset int
+}
+
+// Given the owners of two cells that are reached in the same number of steps, return the
+// owner of a cell that could be reached from either of them
+func combineOwners(first int, second int) int {
+  if first == second {
+    return first
+  }
+  return ownerTied
+}
+
+// Given the map of space, the compressed rows and columns, and the compressed cell of every
+// galaxy, walk out from all of the galaxies at once and return the territory of every cell
+// in the compressed grid. Every step costs at least one, so a cell's owner is settled
+// before it is taken off the queue.
+func claimTerritory(spaceMap []string, rows CompressedAxis, cols CompressedAxis, starts [][2]int) [][]Territory {
+  var territory = make([][]Territory, len(rows.original))
+  for row := range territory {
+    territory[row] = make([]Territory, len(cols.original))
+    for col := range territory[row] {
+      territory[row][col] = Territory{-1, ownerNone}
+    }
+  }
+
+  queue := &PathQueue{}
+  for idx, start := range starts {
+    territory[start[0]][start[1]] = Territory{0, idx}
+    heap.Push(queue, PathNode{row: start[0], col: start[1]})
+  }
+
+  for queue.Len() > 0 {
+    node := heap.Pop(queue).(PathNode)
+    current := territory[node.row][node.col]
+    if node.steps > current.steps {
+      continue
+    }
+
+    var moves = []PathNode{
+      {node.row - 1, node.col, node.steps},
+      {node.row + 1, node.col, node.steps},
+      {node.row, node.col - 1, node.steps},
+      {node.row, node.col + 1, node.steps},
+    }
+    for _, move := range moves {
+      if move.row < 0 || move.row >= len(rows.original) || move.col < 0 || move.col >= len(cols.original) {
+        continue
+      }
+      if spaceMap[rows.original[move.row]][cols.original[move.col]] == ObstacleSymbol {
+        continue
+      }
+
+      switch {
+      case move.row > node.row:
+        move.steps += rows.stepsFrom[move.row]
+      case move.row < node.row:
+        move.steps += rows.stepsFrom[node.row]
+      case move.col > node.col:
+        move.steps += cols.stepsFrom[move.col]
+      default:
+        move.steps += cols.stepsFrom[node.col]
+      }
+
+      // A cell reached in the same number of steps by another galaxy becomes tied.
+      next := &territory[move.row][move.col]
+      if next.steps == -1 || move.steps < next.steps {
+        *next = Territory{move.steps, current.owner}
+        heap.Push(queue, move)
+      } else if move.steps == next.steps {
+        next.owner = combineOwners(next.owner, current.owner)
+      }
+    }
+  }
+  return territory
+}
+
+// Given the territories of the first and last copy of a band which is the given steps
+// across, return the territory of the copy which is offset steps from the first.
+func territoryBetween(first Territory, last Territory, offset int, width int) Territory {
+  switch {
+  case first.steps == -1 && last.steps == -1:
+    return first
+  case first.steps == -1:
+    return Territory{last.steps + width - offset, last.owner}
+  case last.steps == -1:
+    return Territory{first.steps + offset, first.owner}
+  }
+
+  fromFirst := first.steps + offset
+  fromLast := last.steps + width - offset
+  switch {
+  case fromFirst < fromLast:
+    return Territory{fromFirst, first.owner}
+  case fromLast < fromFirst:
+    return Territory{fromLast, last.owner}
+  }
+  return Territory{fromFirst, combineOwners(first.owner, last.owner)}
+}
+
+// Given the territories of the first and last copy of a band which is the given steps
+// across, count up the owners of every copy in between them. Copies are claimed by the
+// first while 2 * offset is less than the steps to the last, less the steps to the first,
+// plus the width, and there is a tie at the one offset where the two are equal.
+func countTerritoryBetween(first Territory, last Territory, width int, regionSizes map[int]int) {
+  between := width - 1
+  if between <= 0 {
+    return
+  }
+  switch {
+  case first.steps == -1:
+    regionSizes[last.owner] += between
+    return
+  case last.steps == -1:
+    regionSizes[first.owner] += between
+    return
+  }
+
+  limit := last.steps - first.steps + width
+  firstCount := min(max(floorDivide(limit - 1, 2), 0), between)
+  tieCount := 0
+  if limit % 2 == 0 && limit / 2 >= 1 && limit / 2 <= between {
+    tieCount = 1
+  }
+  regionSizes[first.owner] += firstCount
+  regionSizes[combineOwners(first.owner, last.owner)] += tieCount
+  regionSizes[last.owner] += between - firstCount - tieCount
+}
+
+// Given a number and a positive divisor, return the number divided by the divisor,
+// rounded down rather than towards zero
+func floorDivide(number int, divisor int) int {
+  quotient := number / divisor
+  if number % divisor != 0 && number < 0 {
+    quotient--
+  }
+  return quotient
+}
+
+// Given the map of space, the compressed rows and columns and the territory of every
+// compressed cell, count up the expanded cells claimed by each galaxy. Tied cells are
+// counted against ownerTied, and cells which can't be reached against ownerNone.
+func countTerritory(spaceMap []string, rows CompressedAxis, cols CompressedAxis, territory [][]Territory) map[int]int {
+  var regionSizes = make(map[int]int)
+  isObstacle := func(row int, col int) bool {
+    return spaceMap[rows.original[row]][cols.original[col]] == ObstacleSymbol
+  }
+
+  for row := range territory {
+    for col := range territory[row] {
+      if isObstacle(row, col) {
+        continue
+      }
+
+      // The cell itself, along with the copies between it and the cells above and to the
+      // left of it.
+      cell := territory[row][col]
+      if cell.steps == -1 {
+        regionSizes[ownerNone]++
+      } else {
+        regionSizes[cell.owner]++
+      }
+      if col > 0 {
+        countTerritoryBetween(territory[row][col - 1], cell, cols.stepsFrom[col], regionSizes)
+      }
+      if row > 0 {
+        countTerritoryBetween(territory[row - 1][col], cell, rows.stepsFrom[row], regionSizes)
+      }
+
+      // Where an expanded row crosses an expanded column, each row of copies between them is
+      // counted in the same way, after working out the territory at either end of it.
+      if row > 0 && col > 0 && rows.stepsFrom[row] > 1 && cols.stepsFrom[col] > 1 {
+        height := rows.stepsFrom[row]
+        for offset := 1; offset < height; offset++ {
+          first := territoryBetween(territory[row - 1][col - 1], territory[row][col - 1], offset, height)
+          last := territoryBetween(territory[row - 1][col], cell, offset, height)
+          countTerritoryBetween(first, last, cols.stepsFrom[col], regionSizes)
+        }
+      }
+    }
+  }
+  return regionSizes
+}
+
+// Given a compressed axis, return where every expanded line along it sits within the
+// compressed grid.
+func locateExpandedLines(axis CompressedAxis) []TerritoryLocator {
+  var locators []TerritoryLocator
+  for idx := range axis.original {
+    if idx > 0 {
+      for offset := 1; offset < axis.stepsFrom[idx]; offset++ {
+        locators = append(locators, TerritoryLocator{idx - 1, offset})
+      }
+    }
+    locators = append(locators, TerritoryLocator{idx, 0})
+  }
+  return locators
+}
+
+// Given the compressed rows and columns, the territory of every compressed cell, and where
+// an expanded cell sits within the compressed grid, return the territory of that cell.
+func territoryAt(rows CompressedAxis, cols CompressedAxis, territory [][]Territory, row TerritoryLocator, col TerritoryLocator) Territory {
+  // Work out the territory at either end of the row of copies that the cell sits on.
+  rowTerritory := func(colIdx int) Territory {
+    if row.offset == 0 {
+      return territory[row.index][colIdx]
+    }
+    return territoryBetween(territory[row.index][colIdx], territory[row.index + 1][colIdx], row.offset, rows.stepsFrom[row.index + 1])
+  }
+  if col.offset == 0 {
+    return rowTerritory(col.index)
+  }
+  return territoryBetween(rowTerritory(col.index), rowTerritory(col.index + 1), col.offset, cols.stepsFrom[col.index + 1])
+}
+
+// Given the map of space, the galaxies within it, the compressed rows and columns, the
+// territory of every compressed cell and a file to write to, draw out who owns every cell
+// of the expanded universe. Owned cells are labelled with the number of their galaxy,
+// galaxies themselves with #, tied cells with TieSymbol and cells which can't be reached
+// are left blank.
+func renderTerritory(spaceMap []string, galaxyList []map[string]int, rows CompressedAxis, cols CompressedAxis, territory [][]Territory, filename string) error {
+  if strings.HasSuffix(strings.ToLower(filename), ".png") {
+    return fmt.Errorf("territories can only be rendered as text")
+  }
+
+  rowLocators := locateExpandedLines(rows)
+  colLocators := locateExpandedLines(cols)
+  if len(colLocators) > MaxRenderCells / len(rowLocators) {
+    return fmt.Errorf("expanded universe of %vx%v is too large to render", len(colLocators), len(rowLocators))
+  }
+
+  file, err := os.Create(filename)
+  if err != nil {
+    return err
+  }
+  defer file.Close()
+
+  cellWidth := len(strconv.Itoa(len(galaxyList)))
+  writer := bufio.NewWriter(file)
+  for _, row := range rowLocators {
+    for _, col := range colLocators {
+      cell := territoryAt(rows, cols, territory, row, col)
+      switch {
+      case spaceMap[rows.original[row.index]][cols.original[col.index]] == ObstacleSymbol:
+        writer.WriteString(strings.Repeat(string(ObstacleSymbol), cellWidth))
+      case cell.steps == -1:
+        writer.WriteString(strings.Repeat(" ", cellWidth))
+      case cell.steps == 0:
+        writer.WriteString(strings.Repeat("#", cellWidth))
+      case cell.owner == ownerTied:
+        writer.WriteString(strings.Repeat(string(TieSymbol), cellWidth))
+      default:
+        label := strconv.Itoa(cell.owner + 1)
+        writer.WriteString(strings.Repeat(".", cellWidth - len(label)) + label)
+      }
+    }
+    writer.WriteString("\n")
+  }
+  return writer.Flush()
+}
+
+// Given the map of space, the galaxies within it, the expansion rates along each axis, the
+// empty rows and columns, and a file to render to (if any), split the expanded universe
+// up between the galaxies and print out the number of cells that each one claims.
+func printTerritories(spaceMap []string, galaxyList []map[string]int, xRate int, yRate int, xLines []int, yLines []int, renderFile string) error {
+  if xRate < 0 || yRate < 0 {
+    return fmt.Errorf("territories can only be claimed with expansion rates of 0 or more")
+  }
+  if len(spaceMap) == 0 {
+    return nil
+  }
+  if err := checkRowWidths(spaceMap, "claiming territories"); err != nil {
+    return err
+  }
+
+  // Every expanded cell is counted up one by one along the rows where expanded lines
+  // cross, and the total number of cells has to fit, so check both before starting.
+  width, height := expandedSize(spaceMap, xRate, yRate, xLines, yLines)
+  if width > math.MaxInt / height || len(xLines) * len(yLines) > MaxTerritoryRows / max(yRate, 1) {
+    return fmt.Errorf("expanded universe of %vx%v is too large to split into territories", width, height)
+  }
+
+  rows := compressAxis(len(spaceMap), xLines, yRate)
+  cols := compressAxis(len(spaceMap[0]), yLines, xRate)
+
+  var starts [][2]int
+  for _, galaxy := range galaxyList {
+    row, col := compressedCell(rows, cols, galaxy)
+    starts = append(starts, [2]int{row, col})
+  }
+
+  territory := claimTerritory(spaceMap, rows, cols, starts)
+  regionSizes := countTerritory(spaceMap, rows, cols, territory)
+  for idx := range galaxyList {
+    fmt.Printf("Galaxy %v: %v cells\n", idx + 1, regionSizes[idx])
+  }
+  fmt.Printf("Tied: %v cells\n", regionSizes[ownerTied])
+  if regionSizes[ownerNone] > 0 {
+    fmt.Printf("Unreachable: %v cells\n", regionSizes[ownerNone])
+  }
+
+  if renderFile != "" {
+    err := renderTerritory(spaceMap, galaxyList, rows, cols, territory, renderFile)
+    if err != nil {
+      return err
+    }
+    debugLine(fmt.Sprintf("Rendered territories to %v", renderFile))
+  }
+  return nil
+}
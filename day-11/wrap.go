@@ -0,0 +1,106 @@
+// Toroidal universes for day 11. When a map wraps around at its edges, the steps between
+// two galaxies along each axis are whichever is shorter out of going straight there, or
+// going the other way around and coming back in from the far side.
+package main
+
+import (
+  "fmt"
+  "math/big"
+  "slices"
+)
+
+// Given a list of galaxies, the expanded width and height of the universe, the metric to
+// measure with and whether to compare every pair directly or use big integers, print out
+// the total steps between every galaxy when the universe wraps around. Only the manhattan
+// metric can be split into its axes like this.
+func printWrappedDistances(galaxyList []map[string]int, width int, height int, metric string, pairwise bool, forceBig bool) error {
+  if metric != "manhattan" {
+    return fmt.Errorf("wrapped universes can only be measured with the manhattan metric")
+  }
+
+  // Wrapping around can only ever make a pair closer, so the same overflow check holds.
+  if pairwise {
+    fmt.Println(calculateWrappedStepsPairwise(galaxyList, width, height))
+  } else if forceBig || stepsMayOverflow(galaxyList) {
+    debugLine("Total steps may overflow, counting with big integers")
+    stepCount := sumWrappedDifferencesBig(axisPositions(galaxyList, "x"), width)
+    fmt.Println(stepCount.Add(stepCount, sumWrappedDifferencesBig(axisPositions(galaxyList, "y"), height)))
+  } else {
+    fmt.Println(sumWrappedDifferences(axisPositions(galaxyList, "x"), width) +
+      sumWrappedDifferences(axisPositions(galaxyList, "y"), height))
+  }
+  return nil
+}
+
+// Given a list of positions around a loop of the given length, sum up the shorter distance
+// between every pair of them. Once the positions are sorted, the positions before index i
+// that are within half a loop of it are reached by going straight there, and the rest are
+// reached quicker by going the other way around. The boundary between the two only ever
+// moves forwards, so we can follow it along with a prefix sum of the positions. The
+// positions are sorted in place.
+func sumWrappedDifferences(positions []int, length int) int {
+  slices.Sort(positions)
+
+  prefixSums := make([]int, len(positions) + 1)
+  for idx, position := range positions {
+    prefixSums[idx + 1] = prefixSums[idx] + position
+  }
+
+  stepCount := 0
+  boundary := 0
+  for idx, position := range positions {
+    for 2 * (position - positions[boundary]) > length {
+      boundary++
+    }
+    near := idx - boundary
+    stepCount += near * position - (prefixSums[idx] - prefixSums[boundary])
+    stepCount += boundary * (length - position) + prefixSums[boundary]
+  }
+  return stepCount
+}
+
+// Given a list of positions around a loop of the given length, sum up the shorter distance
+// between every pair of them with big integers, following sumWrappedDifferences.
+func sumWrappedDifferencesBig(positions []int, length int) *big.Int {
+  slices.Sort(positions)
+
+  prefixSums := make([]*big.Int, len(positions) + 1)
+  prefixSums[0] = new(big.Int)
+  for idx, position := range positions {
+    prefixSums[idx + 1] = new(big.Int).Add(prefixSums[idx], big.NewInt(int64(position)))
+  }
+
+  stepCount := new(big.Int)
+  boundary := 0
+  for idx, position := range positions {
+    for 2 * (position - positions[boundary]) > length {
+      boundary++
+    }
+    near := big.NewInt(int64(idx - boundary))
+    stepCount.Add(stepCount, near.Mul(near, big.NewInt(int64(position))))
+    stepCount.Sub(stepCount, new(big.Int).Sub(prefixSums[idx], prefixSums[boundary]))
+
+    far := big.NewInt(int64(boundary))
+    stepCount.Add(stepCount, far.Mul(far, big.NewInt(int64(length - position))))
+    stepCount.Add(stepCount, prefixSums[boundary])
+  }
+  return stepCount
+}
+
+// Given a list of galaxies and the expanded width and height of the universe, calculate
+// the steps between every galaxy when the universe wraps around by comparing every pair in
+// turn. This is used to cross-check the sorted approach, so the total is always kept with
+// big integers.
+func calculateWrappedStepsPairwise(galaxyList []map[string]int, width int, height int) *big.Int {
+  stepCount := new(big.Int)
+  for i := 0; i < len(galaxyList); i++ {
+    for j := i + 1; j < len(galaxyList); j++ {
+      xSteps := galaxyList[i]["x"] - galaxyList[j]["x"]
+      xSteps = max(xSteps, -xSteps)
+      ySteps := galaxyList[i]["y"] - galaxyList[j]["y"]
+      ySteps = max(ySteps, -ySteps)
+      stepCount.Add(stepCount, big.NewInt(int64(min(xSteps, width - xSteps) + min(ySteps, height - ySteps))))
+    }
+  }
+  return stepCount
+}
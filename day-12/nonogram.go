@@ -8,11 +8,9 @@ import (
   "bufio"
   "flag"
   "fmt"
-  "hash/fnv"
   "os"
   "regexp"
   "strconv"
-  "strings"
 )
 
 // ValidLineCheck - the measure of whether a line we read in is a valid puzzle input
@@ -91,71 +89,65 @@ func breakItemDescriptions(itemDesc []string, folds int) []ItemLog {
   return itemLogs
 }
 
-func hashLogAndPuzzle(logLine string, puzzle []int) uint32 {
-  puzzTotal := 0
-  for _, puzzLen := range puzzle {
-    puzzTotal += puzzLen
+// Count the number of ways that the unknown items within a log line can be filled in to
+// match the given groups of damaged items. This works through the line one item at a time,
+// keeping a count of the ways to reach every (group index, run length) state, where the
+// group index is the number of groups finished so far and the run length is the number of
+// damaged items placed so far against the next group. Every state has its own entry in the
+// table, so no two states can ever share a count.
+func countArrangements(logLine string, puzzleLayout []int) uint64 {
+  longestGroup := 0
+  for _, groupLen := range puzzleLayout {
+    longestGroup = max(longestGroup, groupLen)
   }
-  workingLine := logLine + strconv.Itoa(puzzTotal)
-  h := fnv.New32a()
-  h.Write([]byte(workingLine))
-  return h.Sum32()
-}
 
-func countArrangements(logLine string, puzzleLayout []int, cache map[uint32]uint64) uint64 {
-  var cacheKey = hashLogAndPuzzle(logLine, puzzleLayout)
-  cacheVal, cacheHit := cache[cacheKey]
-  if cacheHit {
-    debugLine(fmt.Sprintf("Cache hit for (%v,%v) := %v", logLine, puzzleLayout, cacheVal))
-    return cacheVal
+  // Only the counts for the current position and the next are needed at any one time.
+  var states, nextStates [][]uint64
+  for groupIdx := 0; groupIdx <= len(puzzleLayout); groupIdx++ {
+    states = append(states, make([]uint64, longestGroup + 1))
+    nextStates = append(nextStates, make([]uint64, longestGroup + 1))
   }
+  states[0][0] = 1
 
-  if len(logLine) == 0 {
-    if len(puzzleLayout) > 0 {
-      return 0
-    } else {
-      return 1
+  for position := 0; position < len(logLine); position++ {
+    for groupIdx := range nextStates {
+      clear(nextStates[groupIdx])
     }
-  }
 
-  var chrPtr = logLine[0]
-  if chrPtr == '.' {
-    var ptrIdx int
-    for ptrIdx = 0; ptrIdx < len(logLine); ptrIdx++ {
-      if logLine[ptrIdx] != '.' {
-        break
-      }
-    }
-    cache[cacheKey] = countArrangements(logLine[ptrIdx:], puzzleLayout, cache)
-
-  } else if chrPtr == '?' {
-    cache[cacheKey] = countArrangements(logLine[1:], puzzleLayout, cache) + countArrangements("#" + logLine[1:], puzzleLayout, cache)
-
-  } else if chrPtr == '#' {
-    if len(puzzleLayout) > 0 {
-      lenToCheck := puzzleLayout[0]
-      if lenToCheck <= len(logLine) && !strings.ContainsRune(logLine[0:lenToCheck], '.') {
-        if lenToCheck == len(logLine) {
-          if len(puzzleLayout) > 1 {
-            return 0
-          } else {
-            return 1
+    var item = logLine[position]
+    for groupIdx := range states {
+      for runLen, ways := range states[groupIdx] {
+        if ways == 0 {
+          continue
+        }
+
+        // A working item either sits between two groups, or finishes off the current one
+        // once it has reached its full length.
+        if item != '#' {
+          if runLen == 0 {
+            nextStates[groupIdx][0] += ways
+          } else if runLen == puzzleLayout[groupIdx] {
+            nextStates[groupIdx + 1][0] += ways
           }
         }
-        if logLine[lenToCheck] == '#' {
-          return 0
+
+        // A damaged item carries on the current group, as long as it doesn't grow too long.
+        if item != '.' && groupIdx < len(puzzleLayout) && runLen < puzzleLayout[groupIdx] {
+          nextStates[groupIdx][runLen + 1] += ways
         }
-        cache[cacheKey] = countArrangements(logLine[lenToCheck+1:], puzzleLayout[1:], cache)
-      } else {
-        return 0
       }
-    } else {
-      return 0
     }
+    states, nextStates = nextStates, states
   }
 
-  debugLine(fmt.Sprintf("Cache store for (%v,%v) := %v", logLine, puzzleLayout, cache[cacheKey]))
-  return cache[cacheKey]
+  // The line can either end after the last group has been finished off by a working item,
+  // or with the last group running right up to the end.
+  var arrangements = states[len(puzzleLayout)][0]
+  if len(puzzleLayout) > 0 {
+    lastGroup := len(puzzleLayout) - 1
+    arrangements += states[lastGroup][puzzleLayout[lastGroup]]
+  }
+  return arrangements
 }
 
 // Main function to kick the work
@@ -182,8 +174,7 @@ func main() {
   debugLine(fmt.Sprintf("%v", itemLog))
 
   for _, item := range itemLog {
-    var cache = make(map[uint32]uint64)
-    var apprCount = countArrangements(item.logLine, item.nonogram, cache)
+    var apprCount = countArrangements(item.logLine, item.nonogram)
     debugLine(fmt.Sprintf("%v found %v approaches", item, apprCount))
     approaches += apprCount
   }
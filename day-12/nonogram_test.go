@@ -0,0 +1,77 @@
+package main
+
+import (
+  "hash/fnv"
+  "strconv"
+  "strings"
+  "testing"
+)
+
+// Given a line and the groups left to place in it, return the 32-bit key that earlier
+// versions cached their counts against. Two different states can share a key, which is
+// why the counts are no longer cached this way.
+func hashLogAndPuzzle(logLine string, puzzle []int) uint32 {
+  puzzTotal := 0
+  for _, puzzLen := range puzzle {
+    puzzTotal += puzzLen
+  }
+  workingLine := logLine + strconv.Itoa(puzzTotal)
+  h := fnv.New32a()
+  h.Write([]byte(workingLine))
+  return h.Sum32()
+}
+
+// Given a number of groups, return that many groups of one
+func groupsOfOne(count int) []int {
+  var groups []int
+  for i := 0; i < count; i++ {
+    groups = append(groups, 1)
+  }
+  return groups
+}
+
+func TestHashedStatesCollide(t *testing.T) {
+  shortKey := hashLogAndPuzzle(strings.Repeat("?", 93), groupsOfOne(94))
+  longKey := hashLogAndPuzzle(strings.Repeat("?", 229), groupsOfOne(115))
+  if shortKey != longKey {
+    t.Fatalf("expected the two states to share a key, got %#x and %#x", shortKey, longKey)
+  }
+}
+
+func TestCollisionLine(t *testing.T) {
+  fileContents, err := readFile("collision.txt")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  itemLog := breakItemDescriptions(fileContents, 1)
+  if len(itemLog) != 1 {
+    t.Fatalf("expected 1 line in collision.txt, got %v", len(itemLog))
+  }
+  if count := countArrangements(itemLog[0].logLine, itemLog[0].nonogram); count != 117 {
+    t.Fatalf("expected 117 arrangements, got %v", count)
+  }
+}
+
+func TestExample(t *testing.T) {
+  fileContents, err := readFile("test.txt")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  for _, example := range []struct {
+    folds int
+    expected uint64
+  }{
+    {1, 21},
+    {5, 525152},
+  } {
+    var approaches uint64 = 0
+    for _, item := range breakItemDescriptions(fileContents, example.folds) {
+      approaches += countArrangements(item.logLine, item.nonogram)
+    }
+    if approaches != example.expected {
+      t.Errorf("expected %v arrangements with %v folds, got %v", example.expected, example.folds, approaches)
+    }
+  }
+}